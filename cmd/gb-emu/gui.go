@@ -0,0 +1,13 @@
+//go:build !nogui
+
+package main
+
+import (
+	"github.com/indeedhat/gb-emulator/internal/emu"
+	"github.com/indeedhat/gb-emulator/internal/ui"
+)
+
+func runGui(opts emu.Options) {
+	_, window := ui.NewFyneRenderer(opts)
+	window.ShowAndRun()
+}
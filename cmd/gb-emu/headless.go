@@ -0,0 +1,62 @@
+package main
+
+import (
+	"time"
+
+	"github.com/indeedhat/gb-emulator/internal/emu"
+	"github.com/indeedhat/gb-emulator/internal/emu/audio"
+	"github.com/indeedhat/gb-emulator/internal/emu/camera"
+	"github.com/indeedhat/gb-emulator/internal/emu/printer"
+)
+
+// runHeadless runs the rom for the given duration without opening a window
+//
+// frames are discarded, audio and prints are only kept if -audio-wav or -printer are set
+// -camera sets the image seen by the pocket camera, without it the sensor sees flat grey
+func runHeadless(romPath string, opts emu.Options, runFor time.Duration) error {
+	e, ctx, err := emu.NewEmulator(romPath, opts.BootRoms, false)
+	if err != nil {
+		return err
+	}
+
+	if opts.AudioWav != "" {
+		sink, err := audio.RecordWav(opts.AudioWav, uint32(opts.AudioRate))
+		if err != nil {
+			return err
+		}
+
+		e.AttachAudioSink(sink)
+	}
+
+	if opts.CameraSource != "" {
+		source, err := camera.Open(opts.CameraSource)
+		if err != nil {
+			return err
+		}
+
+		e.AttachCameraSource(source)
+	}
+
+	if opts.PrinterDir != "" {
+		e.AttachLinkCable(printer.New(opts.PrinterDir))
+	}
+
+	go func() {
+		for range ctx.FrameCh {
+		}
+	}()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- e.Run()
+	}()
+
+	select {
+	case err = <-errCh:
+	case <-time.After(runFor):
+	}
+
+	e.Stop()
+
+	return err
+}
@@ -5,20 +5,34 @@ import (
 	"log"
 	"os"
 	"runtime/pprof"
+	"time"
 
-	"github.com/indeedhat/gb-emulator/internal/ui"
+	"github.com/indeedhat/gb-emulator/internal/emu"
 )
 
 func main() {
 	var (
-		logFile    string
-		debugMode  bool
-		cpuProfile bool
+		logFile     string
+		debugMode   bool
+		cpuProfile  bool
+		headlessRom string
+		runFor      time.Duration
+		opts        emu.Options
 	)
 
 	flag.StringVar(&logFile, "log", "", "save log to file")
 	flag.BoolVar(&debugMode, "debug", false, "Print out debug logs")
 	flag.BoolVar(&cpuProfile, "profile-cpu", false, "generate a cpu profile")
+	flag.StringVar(&opts.BootRoms.Dmg, "boot-rom", "", "run the given DMG boot rom before the cart")
+	flag.StringVar(&opts.BootRoms.Cgb, "cgb-boot-rom", "", "run the given CGB boot rom before CGB carts")
+	flag.StringVar(&opts.LinkListen, "link-listen", "", "wait for a link cable connection on tcp:host:port or unix:/path")
+	flag.StringVar(&opts.LinkDial, "link-dial", "", "connect a link cable to tcp:host:port or unix:/path")
+	flag.StringVar(&opts.PrinterDir, "printer", "", "connect a game boy printer that saves prints to the given directory")
+	flag.StringVar(&opts.CameraSource, "camera", "", "show the given png or directory of png frames to the pocket camera")
+	flag.StringVar(&opts.AudioWav, "audio-wav", "", "record audio to the given wav file")
+	flag.UintVar(&opts.AudioRate, "audio-rate", 44100, "sample rate of the -audio-wav recording")
+	flag.StringVar(&headlessRom, "headless", "", "run the given rom without a window, use with -audio-wav to record its audio")
+	flag.DurationVar(&runFor, "run-for", 10*time.Second, "how long a -headless run lasts")
 	flag.Parse()
 
 	if cpuProfile {
@@ -42,6 +56,20 @@ func main() {
 		log.SetOutput(fh)
 	}
 
-	_, window := ui.NewFyneRenderer()
-	window.ShowAndRun()
+	if opts.LinkListen != "" && opts.LinkDial != "" {
+		log.Fatal("only one of -link-listen and -link-dial can be used")
+	}
+
+	if opts.PrinterDir != "" && (opts.LinkListen != "" || opts.LinkDial != "") {
+		log.Fatal("-printer cannot be used with a link cable")
+	}
+
+	if headlessRom != "" {
+		if err := runHeadless(headlessRom, opts, runFor); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	runGui(opts)
 }
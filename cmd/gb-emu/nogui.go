@@ -0,0 +1,15 @@
+//go:build nogui
+
+package main
+
+import (
+	"log"
+
+	"github.com/indeedhat/gb-emulator/internal/emu"
+)
+
+// runGui is stubbed out in builds without the gui so -headless can be built without the fyne
+// cgo dependencies
+func runGui(_ emu.Options) {
+	log.Fatal("built without the gui, only -headless runs are supported")
+}
@@ -0,0 +1,311 @@
+package apu
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/indeedhat/gb-emulator/internal/emu/config"
+	"github.com/indeedhat/gb-emulator/internal/emu/context"
+	. "github.com/indeedhat/gb-emulator/internal/emu/types"
+)
+
+// bits that always read back as 1 for each register in 0xFF10 - 0xFF25
+var readMasks = []uint8{
+	0x80, 0x3F, 0x00, 0xFF, 0xBF, // NR10 - NR14
+	0xFF, 0x3F, 0x00, 0xFF, 0xBF, // ---- - NR24
+	0x7F, 0xFF, 0x9F, 0xFF, 0xBF, // NR30 - NR34
+	0xFF, 0xFF, 0x00, 0x00, 0xBF, // ---- - NR44
+	0x00, 0x00, // NR50 - NR51
+}
+
+type Apu struct {
+	enabled bool
+
+	// Bit 7:   VIN left
+	// Bit 6-4: Left volume
+	// Bit 3:   VIN right
+	// Bit 2-0: Right volume
+	masterVolume uint8
+	// Bit 7-4: channel 4-1 left
+	// Bit 3-0: channel 4-1 right
+	panning uint8
+
+	registers []byte
+
+	ch1 *squareChannel
+	ch2 *squareChannel
+	ch3 *waveChannel
+	ch4 *noiseChannel
+
+	frameSeqTicks uint16
+	frameSeqStep  uint8
+	sampleTicks   uint16
+
+	samples []Sample
+
+	ctx *context.Context
+}
+
+func New(ctx *context.Context) {
+	a := &Apu{
+		enabled:      true,
+		masterVolume: 0x77,
+		panning:      0xF3,
+		registers:    make([]byte, len(readMasks)),
+		ch1:          newSquareChannel(true),
+		ch2:          newSquareChannel(false),
+		ch3:          newWaveChannel(),
+		ch4:          newNoiseChannel(),
+		samples:      make([]Sample, 0, config.ApuBufferSize),
+		ctx:          ctx,
+	}
+
+	a.registers[0xFF24-0xFF10] = a.masterVolume
+	a.registers[0xFF25-0xFF10] = a.panning
+
+	ctx.Apu = a
+}
+
+func (a *Apu) LoadState(data []byte) {
+	r := bytes.NewReader(data)
+
+	binary.Read(r, binary.BigEndian, &a.enabled)
+	binary.Read(r, binary.BigEndian, &a.masterVolume)
+	binary.Read(r, binary.BigEndian, &a.panning)
+	r.Read(a.registers)
+
+	a.ch1.loadState(r)
+	a.ch2.loadState(r)
+	a.ch3.loadState(r)
+	a.ch4.loadState(r)
+
+	binary.Read(r, binary.BigEndian, &a.frameSeqTicks)
+	binary.Read(r, binary.BigEndian, &a.frameSeqStep)
+	binary.Read(r, binary.BigEndian, &a.sampleTicks)
+}
+
+func (a *Apu) SaveState() []byte {
+	var buf bytes.Buffer
+
+	binary.Write(&buf, binary.BigEndian, a.enabled)
+	binary.Write(&buf, binary.BigEndian, a.masterVolume)
+	binary.Write(&buf, binary.BigEndian, a.panning)
+	buf.Write(a.registers)
+
+	a.ch1.saveState(&buf)
+	a.ch2.saveState(&buf)
+	a.ch3.saveState(&buf)
+	a.ch4.saveState(&buf)
+
+	binary.Write(&buf, binary.BigEndian, a.frameSeqTicks)
+	binary.Write(&buf, binary.BigEndian, a.frameSeqStep)
+	binary.Write(&buf, binary.BigEndian, a.sampleTicks)
+
+	return buf.Bytes()
+}
+
+func (a *Apu) Read(address uint16) uint8 {
+	switch true {
+	case address >= 0xFF30 && address <= 0xFF3F:
+		return a.ch3.readRam(address)
+	case address == 0xFF26:
+		return a.status()
+	case address >= 0xFF10 && address < 0xFF26:
+		idx := address - 0xFF10
+		return a.registers[idx] | readMasks[idx]
+	default:
+		return 0xFF
+	}
+}
+
+func (a *Apu) Write(address uint16, value uint8) {
+	switch true {
+	case address >= 0xFF30 && address <= 0xFF3F:
+		a.ch3.writeRam(address, value)
+		return
+	case address == 0xFF26:
+		a.setPower(value&0x80 != 0)
+		return
+	case address < 0xFF10 || address > 0xFF25:
+		return
+	}
+
+	// NB: all registers other than NR52 and wave ram are read only while the apu is powered down
+	if !a.enabled {
+		return
+	}
+
+	a.registers[address-0xFF10] = value
+
+	switch true {
+	case address <= 0xFF14:
+		a.ch1.write(uint8(address-0xFF10), value)
+	case address <= 0xFF19:
+		a.ch2.write(uint8(address-0xFF15), value)
+	case address <= 0xFF1E:
+		a.ch3.write(uint8(address-0xFF1A), value)
+	case address <= 0xFF23:
+		a.ch4.write(uint8(address-0xFF1F), value)
+	case address == 0xFF24:
+		a.masterVolume = value
+	case address == 0xFF25:
+		a.panning = value
+	}
+}
+
+func (a *Apu) Tick() {
+	if a.enabled {
+		a.ch1.tick()
+		a.ch2.tick()
+		a.ch3.tick()
+		a.ch4.tick()
+
+		a.frameSeqTicks++
+		if a.frameSeqTicks >= config.ApuFrameSeqTicks {
+			a.frameSeqTicks = 0
+			a.stepFrameSequencer()
+		}
+	}
+
+	a.sampleTicks++
+	if a.sampleTicks < config.ApuTicksPerSample {
+		return
+	}
+
+	a.sampleTicks = 0
+	a.samples = append(a.samples, a.mix())
+
+	if len(a.samples) < config.ApuBufferSize {
+		return
+	}
+
+	if a.ctx.AudioLossless {
+		a.ctx.AudioCh <- a.samples
+	} else {
+		// NB: audio is dropped rather than stalling the emulator if nothing is consuming it
+		select {
+		case a.ctx.AudioCh <- a.samples:
+		default:
+		}
+	}
+
+	a.samples = make([]Sample, 0, config.ApuBufferSize)
+}
+
+// stepFrameSequencer clocks the 512Hz frame sequencer
+//
+// Step   Length Ctr  Vol Env     Sweep
+// ---------------------------------------
+// 0      Clock       -           -
+// 1      -           -           -
+// 2      Clock       -           Clock
+// 3      -           -           -
+// 4      Clock       -           -
+// 5      -           -           -
+// 6      Clock       -           Clock
+// 7      -           Clock       -
+func (a *Apu) stepFrameSequencer() {
+	if a.frameSeqStep%2 == 0 {
+		a.ch1.clockLength()
+		a.ch2.clockLength()
+		a.ch3.clockLength()
+		a.ch4.clockLength()
+	}
+
+	if a.frameSeqStep == 2 || a.frameSeqStep == 6 {
+		a.ch1.clockSweep()
+	}
+
+	if a.frameSeqStep == 7 {
+		a.ch1.clockEnvelope()
+		a.ch2.clockEnvelope()
+		a.ch4.clockEnvelope()
+	}
+
+	a.frameSeqStep = (a.frameSeqStep + 1) & 0x7
+}
+
+func (a *Apu) mix() Sample {
+	if !a.enabled {
+		return Sample{}
+	}
+
+	channels := []float32{
+		dac(a.ch1.output(), a.ch1.dacEnabled()),
+		dac(a.ch2.output(), a.ch2.dacEnabled()),
+		dac(a.ch3.output(), a.ch3.dacEnabled),
+		dac(a.ch4.output(), a.ch4.dacEnabled()),
+	}
+
+	var left, right float32
+	for i, sample := range channels {
+		if a.panning&(1<<(i+4)) != 0 {
+			left += sample
+		}
+		if a.panning&(1<<i) != 0 {
+			right += sample
+		}
+	}
+
+	leftVolume := float32((a.masterVolume>>4)&0x7+1) / 8
+	rightVolume := float32(a.masterVolume&0x7+1) / 8
+
+	return Sample{
+		Left:  int16(left / 4 * leftVolume * 0x7FFF),
+		Right: int16(right / 4 * rightVolume * 0x7FFF),
+	}
+}
+
+func (a *Apu) status() uint8 {
+	var value uint8 = 0x70
+	if a.enabled {
+		value |= 0x80
+	}
+	if a.ch1.enabled {
+		value |= 0x01
+	}
+	if a.ch2.enabled {
+		value |= 0x02
+	}
+	if a.ch3.enabled {
+		value |= 0x04
+	}
+	if a.ch4.enabled {
+		value |= 0x08
+	}
+
+	return value
+}
+
+func (a *Apu) setPower(on bool) {
+	if on == a.enabled {
+		return
+	}
+
+	a.enabled = on
+	if on {
+		a.frameSeqStep = 0
+		return
+	}
+
+	// NB: powering down clears every register but leaves wave ram intact
+	ram := a.ch3.ram
+	a.ch1 = newSquareChannel(true)
+	a.ch2 = newSquareChannel(false)
+	a.ch3 = newWaveChannel()
+	a.ch3.ram = ram
+	a.ch4 = newNoiseChannel()
+
+	a.masterVolume = 0
+	a.panning = 0
+	clear(a.registers)
+}
+
+// dac converts a 4 bit channel output into the range -1 to 1
+func dac(value uint8, enabled bool) float32 {
+	if !enabled {
+		return 0
+	}
+
+	return float32(value)/7.5 - 1
+}
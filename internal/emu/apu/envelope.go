@@ -0,0 +1,103 @@
+package apu
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+type lengthCounter struct {
+	enabled bool
+	max     uint16
+	value   uint16
+}
+
+func (l *lengthCounter) load(value uint16) {
+	l.value = l.max - value
+}
+
+// clock returns true when the counter expires and the channel should be disabled
+func (l *lengthCounter) clock() bool {
+	if !l.enabled || l.value == 0 {
+		return false
+	}
+
+	l.value--
+	return l.value == 0
+}
+
+func (l *lengthCounter) trigger() {
+	if l.value == 0 {
+		l.value = l.max
+	}
+}
+
+func (l *lengthCounter) saveState(buf *bytes.Buffer) {
+	binary.Write(buf, binary.BigEndian, l.enabled)
+	binary.Write(buf, binary.BigEndian, l.value)
+}
+
+func (l *lengthCounter) loadState(r *bytes.Reader) {
+	binary.Read(r, binary.BigEndian, &l.enabled)
+	binary.Read(r, binary.BigEndian, &l.value)
+}
+
+type volumeEnvelope struct {
+	initial  uint8
+	increase bool
+	pace     uint8
+	timer    uint8
+	volume   uint8
+}
+
+// write updates the envelope from an NRx2 register value
+func (e *volumeEnvelope) write(value uint8) {
+	e.initial = value >> 4
+	e.increase = value&0x08 != 0
+	e.pace = value & 0x07
+}
+
+// dacEnabled reports if the upper 5 bits of NRx2 are set, without them the channel is silent
+func (e *volumeEnvelope) dacEnabled() bool {
+	return e.initial != 0 || e.increase
+}
+
+func (e *volumeEnvelope) trigger() {
+	e.volume = e.initial
+	e.timer = e.pace
+}
+
+func (e *volumeEnvelope) clock() {
+	if e.pace == 0 {
+		return
+	}
+
+	if e.timer > 0 {
+		e.timer--
+	}
+	if e.timer != 0 {
+		return
+	}
+
+	e.timer = e.pace
+	if e.increase && e.volume < 0xF {
+		e.volume++
+	} else if !e.increase && e.volume > 0 {
+		e.volume--
+	}
+}
+
+func (e *volumeEnvelope) saveState(buf *bytes.Buffer) {
+	binary.Write(buf, binary.BigEndian, e.initial)
+	binary.Write(buf, binary.BigEndian, e.increase)
+	binary.Write(buf, binary.BigEndian, e.pace)
+	binary.Write(buf, binary.BigEndian, e.timer)
+	binary.Write(buf, binary.BigEndian, e.volume)
+}
+
+func (e *volumeEnvelope) loadState(r *bytes.Reader) {
+	binary.Read(r, binary.BigEndian, &e.initial)
+	binary.Read(r, binary.BigEndian, &e.increase)
+	binary.Read(r, binary.BigEndian, &e.pace)
+	binary.Read(r, binary.BigEndian, &e.timer)
+	binary.Read(r, binary.BigEndian, &e.volume)
+}
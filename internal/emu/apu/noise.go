@@ -0,0 +1,131 @@
+package apu
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+var noiseDivisors = []uint16{8, 16, 32, 48, 64, 80, 96, 112}
+
+type noiseChannel struct {
+	enabled bool
+
+	clockShift  uint8
+	shortMode   bool
+	divisorCode uint8
+	periodTimer uint16
+	lfsr        uint16
+
+	length   lengthCounter
+	envelope volumeEnvelope
+}
+
+func newNoiseChannel() *noiseChannel {
+	return &noiseChannel{
+		length: lengthCounter{max: 64},
+		lfsr:   0x7FFF,
+	}
+}
+
+func (n *noiseChannel) write(reg uint8, value uint8) {
+	switch reg {
+	case 1:
+		n.length.load(uint16(value & 0x3F))
+	case 2:
+		n.envelope.write(value)
+		if !n.envelope.dacEnabled() {
+			n.enabled = false
+		}
+	case 3:
+		n.clockShift = value >> 4
+		n.shortMode = value&0x08 != 0
+		n.divisorCode = value & 0x07
+	case 4:
+		n.length.enabled = value&0x40 != 0
+
+		if value&0x80 != 0 {
+			n.trigger()
+		}
+	}
+}
+
+func (n *noiseChannel) trigger() {
+	n.enabled = n.envelope.dacEnabled()
+	n.length.trigger()
+	n.envelope.trigger()
+	n.periodTimer = n.timerPeriod()
+	n.lfsr = 0x7FFF
+}
+
+func (n *noiseChannel) timerPeriod() uint16 {
+	return noiseDivisors[n.divisorCode] << n.clockShift
+}
+
+func (n *noiseChannel) tick() {
+	if n.periodTimer > 0 {
+		n.periodTimer--
+	}
+	if n.periodTimer != 0 {
+		return
+	}
+
+	n.periodTimer = n.timerPeriod()
+
+	// NB: clock shifts of 14 and 15 stop the lfsr from being clocked at all
+	if n.clockShift >= 14 {
+		return
+	}
+
+	bit := (n.lfsr ^ (n.lfsr >> 1)) & 0x1
+	n.lfsr = (n.lfsr >> 1) | bit<<14
+
+	if n.shortMode {
+		n.lfsr = n.lfsr&^(1<<6) | bit<<6
+	}
+}
+
+func (n *noiseChannel) clockLength() {
+	if n.length.clock() {
+		n.enabled = false
+	}
+}
+
+func (n *noiseChannel) clockEnvelope() {
+	n.envelope.clock()
+}
+
+func (n *noiseChannel) dacEnabled() bool {
+	return n.envelope.dacEnabled()
+}
+
+func (n *noiseChannel) output() uint8 {
+	if !n.enabled || n.lfsr&0x1 != 0 {
+		return 0
+	}
+
+	return n.envelope.volume
+}
+
+func (n *noiseChannel) saveState(buf *bytes.Buffer) {
+	binary.Write(buf, binary.BigEndian, n.enabled)
+	binary.Write(buf, binary.BigEndian, n.clockShift)
+	binary.Write(buf, binary.BigEndian, n.shortMode)
+	binary.Write(buf, binary.BigEndian, n.divisorCode)
+	binary.Write(buf, binary.BigEndian, n.periodTimer)
+	binary.Write(buf, binary.BigEndian, n.lfsr)
+
+	n.length.saveState(buf)
+	n.envelope.saveState(buf)
+}
+
+func (n *noiseChannel) loadState(r *bytes.Reader) {
+	binary.Read(r, binary.BigEndian, &n.enabled)
+	binary.Read(r, binary.BigEndian, &n.clockShift)
+	binary.Read(r, binary.BigEndian, &n.shortMode)
+	binary.Read(r, binary.BigEndian, &n.divisorCode)
+	binary.Read(r, binary.BigEndian, &n.periodTimer)
+	binary.Read(r, binary.BigEndian, &n.lfsr)
+
+	n.length.loadState(r)
+	n.envelope.loadState(r)
+}
@@ -0,0 +1,214 @@
+package apu
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+var dutyPatterns = []uint8{
+	/* 12.5% */ 0b00000001,
+	/* 25%   */ 0b10000001,
+	/* 50%   */ 0b10000111,
+	/* 75%   */ 0b01111110,
+}
+
+type squareChannel struct {
+	enabled bool
+
+	duty        uint8
+	dutyStep    uint8
+	period      uint16
+	periodTimer uint16
+
+	length   lengthCounter
+	envelope volumeEnvelope
+
+	// sweep is only wired up on channel 1
+	hasSweep     bool
+	sweepEnabled bool
+	sweepPace    uint8
+	sweepNegate  bool
+	sweepStep    uint8
+	sweepTimer   uint8
+	sweepShadow  uint16
+}
+
+func newSquareChannel(hasSweep bool) *squareChannel {
+	return &squareChannel{
+		hasSweep: hasSweep,
+		length:   lengthCounter{max: 64},
+	}
+}
+
+func (s *squareChannel) write(reg uint8, value uint8) {
+	switch reg {
+	case 0:
+		if !s.hasSweep {
+			return
+		}
+
+		s.sweepPace = (value >> 4) & 0x07
+		s.sweepNegate = value&0x08 != 0
+		s.sweepStep = value & 0x07
+	case 1:
+		s.duty = value >> 6
+		s.length.load(uint16(value & 0x3F))
+	case 2:
+		s.envelope.write(value)
+		if !s.envelope.dacEnabled() {
+			s.enabled = false
+		}
+	case 3:
+		s.period = s.period&0x700 | uint16(value)
+	case 4:
+		s.period = s.period&0xFF | uint16(value&0x07)<<8
+		s.length.enabled = value&0x40 != 0
+
+		if value&0x80 != 0 {
+			s.trigger()
+		}
+	}
+}
+
+func (s *squareChannel) trigger() {
+	s.enabled = s.envelope.dacEnabled()
+	s.length.trigger()
+	s.envelope.trigger()
+	s.periodTimer = (2048 - s.period) * 4
+
+	if !s.hasSweep {
+		return
+	}
+
+	s.sweepShadow = s.period
+	s.sweepTimer = s.sweepPace
+	if s.sweepTimer == 0 {
+		s.sweepTimer = 8
+	}
+	s.sweepEnabled = s.sweepPace != 0 || s.sweepStep != 0
+
+	if s.sweepStep != 0 {
+		s.calculateSweep()
+	}
+}
+
+func (s *squareChannel) tick() {
+	if s.periodTimer > 0 {
+		s.periodTimer--
+	}
+	if s.periodTimer != 0 {
+		return
+	}
+
+	s.periodTimer = (2048 - s.period) * 4
+	s.dutyStep = (s.dutyStep + 1) & 0x7
+}
+
+func (s *squareChannel) clockLength() {
+	if s.length.clock() {
+		s.enabled = false
+	}
+}
+
+func (s *squareChannel) clockEnvelope() {
+	s.envelope.clock()
+}
+
+func (s *squareChannel) clockSweep() {
+	if !s.hasSweep {
+		return
+	}
+
+	if s.sweepTimer > 0 {
+		s.sweepTimer--
+	}
+	if s.sweepTimer != 0 {
+		return
+	}
+
+	s.sweepTimer = s.sweepPace
+	if s.sweepTimer == 0 {
+		s.sweepTimer = 8
+	}
+
+	if !s.sweepEnabled || s.sweepPace == 0 {
+		return
+	}
+
+	period := s.calculateSweep()
+	if period > 0x7FF || s.sweepStep == 0 {
+		return
+	}
+
+	s.sweepShadow = period
+	s.period = period
+	s.calculateSweep()
+}
+
+// calculateSweep works out the next period of the sweep unit disabling the channel on overflow
+func (s *squareChannel) calculateSweep() uint16 {
+	delta := s.sweepShadow >> s.sweepStep
+
+	period := s.sweepShadow + delta
+	if s.sweepNegate {
+		period = s.sweepShadow - delta
+	}
+
+	if period > 0x7FF {
+		s.enabled = false
+	}
+
+	return period
+}
+
+func (s *squareChannel) dacEnabled() bool {
+	return s.envelope.dacEnabled()
+}
+
+func (s *squareChannel) output() uint8 {
+	if !s.enabled {
+		return 0
+	}
+
+	if dutyPatterns[s.duty]>>s.dutyStep&0x1 == 0 {
+		return 0
+	}
+
+	return s.envelope.volume
+}
+
+func (s *squareChannel) saveState(buf *bytes.Buffer) {
+	binary.Write(buf, binary.BigEndian, s.enabled)
+	binary.Write(buf, binary.BigEndian, s.duty)
+	binary.Write(buf, binary.BigEndian, s.dutyStep)
+	binary.Write(buf, binary.BigEndian, s.period)
+	binary.Write(buf, binary.BigEndian, s.periodTimer)
+
+	s.length.saveState(buf)
+	s.envelope.saveState(buf)
+
+	binary.Write(buf, binary.BigEndian, s.sweepEnabled)
+	binary.Write(buf, binary.BigEndian, s.sweepPace)
+	binary.Write(buf, binary.BigEndian, s.sweepNegate)
+	binary.Write(buf, binary.BigEndian, s.sweepStep)
+	binary.Write(buf, binary.BigEndian, s.sweepTimer)
+	binary.Write(buf, binary.BigEndian, s.sweepShadow)
+}
+
+func (s *squareChannel) loadState(r *bytes.Reader) {
+	binary.Read(r, binary.BigEndian, &s.enabled)
+	binary.Read(r, binary.BigEndian, &s.duty)
+	binary.Read(r, binary.BigEndian, &s.dutyStep)
+	binary.Read(r, binary.BigEndian, &s.period)
+	binary.Read(r, binary.BigEndian, &s.periodTimer)
+
+	s.length.loadState(r)
+	s.envelope.loadState(r)
+
+	binary.Read(r, binary.BigEndian, &s.sweepEnabled)
+	binary.Read(r, binary.BigEndian, &s.sweepPace)
+	binary.Read(r, binary.BigEndian, &s.sweepNegate)
+	binary.Read(r, binary.BigEndian, &s.sweepStep)
+	binary.Read(r, binary.BigEndian, &s.sweepTimer)
+	binary.Read(r, binary.BigEndian, &s.sweepShadow)
+}
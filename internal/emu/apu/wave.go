@@ -0,0 +1,126 @@
+package apu
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+type waveChannel struct {
+	enabled    bool
+	dacEnabled bool
+
+	outputLevel uint8
+	period      uint16
+	periodTimer uint16
+	position    uint8
+	sample      uint8
+
+	length lengthCounter
+
+	ram []byte
+}
+
+func newWaveChannel() *waveChannel {
+	return &waveChannel{
+		length: lengthCounter{max: 256},
+		ram:    make([]byte, 16),
+	}
+}
+
+func (w *waveChannel) write(reg uint8, value uint8) {
+	switch reg {
+	case 0:
+		w.dacEnabled = value&0x80 != 0
+		if !w.dacEnabled {
+			w.enabled = false
+		}
+	case 1:
+		w.length.load(uint16(value))
+	case 2:
+		w.outputLevel = (value >> 5) & 0x03
+	case 3:
+		w.period = w.period&0x700 | uint16(value)
+	case 4:
+		w.period = w.period&0xFF | uint16(value&0x07)<<8
+		w.length.enabled = value&0x40 != 0
+
+		if value&0x80 != 0 {
+			w.trigger()
+		}
+	}
+}
+
+func (w *waveChannel) trigger() {
+	w.enabled = w.dacEnabled
+	w.length.trigger()
+	w.periodTimer = (2048 - w.period) * 2
+	w.position = 0
+}
+
+func (w *waveChannel) tick() {
+	if w.periodTimer > 0 {
+		w.periodTimer--
+	}
+	if w.periodTimer != 0 {
+		return
+	}
+
+	w.periodTimer = (2048 - w.period) * 2
+	w.position = (w.position + 1) & 0x1F
+
+	w.sample = w.ram[w.position/2]
+	if w.position%2 == 0 {
+		w.sample >>= 4
+	}
+	w.sample &= 0x0F
+}
+
+func (w *waveChannel) clockLength() {
+	if w.length.clock() {
+		w.enabled = false
+	}
+}
+
+func (w *waveChannel) output() uint8 {
+	if !w.enabled || w.outputLevel == 0 {
+		return 0
+	}
+
+	return w.sample >> (w.outputLevel - 1)
+}
+
+func (w *waveChannel) readRam(address uint16) uint8 {
+	return w.ram[address-0xFF30]
+}
+
+func (w *waveChannel) writeRam(address uint16, value uint8) {
+	w.ram[address-0xFF30] = value
+}
+
+func (w *waveChannel) saveState(buf *bytes.Buffer) {
+	binary.Write(buf, binary.BigEndian, w.enabled)
+	binary.Write(buf, binary.BigEndian, w.dacEnabled)
+	binary.Write(buf, binary.BigEndian, w.outputLevel)
+	binary.Write(buf, binary.BigEndian, w.period)
+	binary.Write(buf, binary.BigEndian, w.periodTimer)
+	binary.Write(buf, binary.BigEndian, w.position)
+	binary.Write(buf, binary.BigEndian, w.sample)
+
+	w.length.saveState(buf)
+
+	buf.Write(w.ram)
+}
+
+func (w *waveChannel) loadState(r *bytes.Reader) {
+	binary.Read(r, binary.BigEndian, &w.enabled)
+	binary.Read(r, binary.BigEndian, &w.dacEnabled)
+	binary.Read(r, binary.BigEndian, &w.outputLevel)
+	binary.Read(r, binary.BigEndian, &w.period)
+	binary.Read(r, binary.BigEndian, &w.periodTimer)
+	binary.Read(r, binary.BigEndian, &w.position)
+	binary.Read(r, binary.BigEndian, &w.sample)
+
+	w.length.loadState(r)
+
+	r.Read(w.ram)
+}
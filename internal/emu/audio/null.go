@@ -0,0 +1,22 @@
+package audio
+
+import . "github.com/indeedhat/gb-emulator/internal/emu/types"
+
+// NullSink discards all audio written to it
+type NullSink struct{}
+
+func NewNullSink() *NullSink {
+	return &NullSink{}
+}
+
+// Write implements AudioSink.
+func (n *NullSink) Write(_ []Sample) error {
+	return nil
+}
+
+// Close implements AudioSink.
+func (n *NullSink) Close() error {
+	return nil
+}
+
+var _ AudioSink = (*NullSink)(nil)
@@ -0,0 +1,74 @@
+package audio
+
+import (
+	"github.com/indeedhat/gb-emulator/internal/emu/config"
+	. "github.com/indeedhat/gb-emulator/internal/emu/types"
+)
+
+// Resampler converts audio from the apu sample rate to the output rate of the wrapped sink
+// using linear interpolation
+type Resampler struct {
+	sink AudioSink
+
+	step   float64
+	pos    float64
+	prev   Sample
+	primed bool
+}
+
+func NewResampler(sink AudioSink, outputRate uint32) *Resampler {
+	return &Resampler{
+		sink: sink,
+		step: float64(config.ApuSampleRate) / float64(outputRate),
+	}
+}
+
+// Write implements AudioSink.
+func (r *Resampler) Write(samples []Sample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	if !r.primed {
+		r.prev = samples[0]
+		r.primed = true
+	}
+
+	// NB: index 0 is the last sample of the previous write so interpolation carries across buffers
+	at := func(i int) Sample {
+		if i == 0 {
+			return r.prev
+		}
+		return samples[i-1]
+	}
+
+	out := make([]Sample, 0, int(float64(len(samples))/r.step)+1)
+	for int(r.pos)+1 <= len(samples) {
+		i := int(r.pos)
+		frac := r.pos - float64(i)
+		a, b := at(i), at(i+1)
+
+		out = append(out, Sample{
+			Left:  lerp(a.Left, b.Left, frac),
+			Right: lerp(a.Right, b.Right, frac),
+		})
+
+		r.pos += r.step
+	}
+
+	r.pos -= float64(len(samples))
+	r.prev = samples[len(samples)-1]
+
+	return r.sink.Write(out)
+}
+
+// Close implements AudioSink.
+func (r *Resampler) Close() error {
+	return r.sink.Close()
+}
+
+func lerp(a, b int16, frac float64) int16 {
+	return int16(float64(a) + (float64(b)-float64(a))*frac)
+}
+
+var _ AudioSink = (*Resampler)(nil)
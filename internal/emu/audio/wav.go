@@ -0,0 +1,116 @@
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+
+	. "github.com/indeedhat/gb-emulator/internal/emu/types"
+)
+
+const (
+	wavHeaderSize    = 44
+	wavChannels      = 2
+	wavBitsPerSample = 16
+)
+
+// WavSink records 16bit stereo PCM audio to a .wav file
+type WavSink struct {
+	fh         *os.File
+	sampleRate uint32
+	dataSize   uint32
+}
+
+// RecordWav records apu audio to a .wav file resampled to the given rate
+func RecordWav(path string, sampleRate uint32) (*Resampler, error) {
+	sink, err := NewWavSink(path, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewResampler(sink, sampleRate), nil
+}
+
+func NewWavSink(path string, sampleRate uint32) (*WavSink, error) {
+	fh, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &WavSink{
+		fh:         fh,
+		sampleRate: sampleRate,
+	}
+
+	// NB: sizes are not known until the file is closed so the header gets rewritten then
+	if err := w.writeHeader(); err != nil {
+		fh.Close()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Write implements AudioSink.
+func (w *WavSink) Write(samples []Sample) error {
+	if err := binary.Write(w.fh, binary.LittleEndian, samples); err != nil {
+		return err
+	}
+
+	w.dataSize += uint32(len(samples)) * wavChannels * wavBitsPerSample / 8
+
+	return nil
+}
+
+// Close implements AudioSink.
+func (w *WavSink) Close() error {
+	if _, err := w.fh.Seek(0, io.SeekStart); err != nil {
+		w.fh.Close()
+		return err
+	}
+
+	if err := w.writeHeader(); err != nil {
+		w.fh.Close()
+		return err
+	}
+
+	return w.fh.Close()
+}
+
+func (w *WavSink) writeHeader() error {
+	blockAlign := uint16(wavChannels * wavBitsPerSample / 8)
+
+	header := struct {
+		ChunkId       [4]byte
+		ChunkSize     uint32
+		Format        [4]byte
+		FmtChunkId    [4]byte
+		FmtChunkSize  uint32
+		AudioFormat   uint16
+		NumChannels   uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+		DataChunkId   [4]byte
+		DataChunkSize uint32
+	}{
+		ChunkId:       [4]byte{'R', 'I', 'F', 'F'},
+		ChunkSize:     wavHeaderSize - 8 + w.dataSize,
+		Format:        [4]byte{'W', 'A', 'V', 'E'},
+		FmtChunkId:    [4]byte{'f', 'm', 't', ' '},
+		FmtChunkSize:  16,
+		AudioFormat:   1,
+		NumChannels:   wavChannels,
+		SampleRate:    w.sampleRate,
+		ByteRate:      w.sampleRate * uint32(blockAlign),
+		BlockAlign:    blockAlign,
+		BitsPerSample: wavBitsPerSample,
+		DataChunkId:   [4]byte{'d', 'a', 't', 'a'},
+		DataChunkSize: w.dataSize,
+	}
+
+	return binary.Write(w.fh, binary.LittleEndian, header)
+}
+
+var _ AudioSink = (*WavSink)(nil)
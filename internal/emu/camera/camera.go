@@ -0,0 +1,21 @@
+package camera
+
+import (
+	"os"
+
+	. "github.com/indeedhat/gb-emulator/internal/emu/types"
+)
+
+// Open creates a camera source from either a single png image or a directory of png frames
+func Open(path string) (CameraSource, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return NewDirSource(path)
+	}
+
+	return NewStillSource(path)
+}
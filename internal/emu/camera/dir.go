@@ -0,0 +1,60 @@
+package camera
+
+import (
+	"errors"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	. "github.com/indeedhat/gb-emulator/internal/emu/types"
+)
+
+// DirSource plays back a directory of png frames in filename order, one frame per capture
+// looping back to the first frame after the last
+type DirSource struct {
+	paths []string
+	next  int
+}
+
+func NewDirSource(dir string) (*DirSource, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &DirSource{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".png") {
+			continue
+		}
+
+		s.paths = append(s.paths, filepath.Join(dir, entry.Name()))
+	}
+
+	if len(s.paths) == 0 {
+		return nil, errors.New("no png frames found in camera source directory")
+	}
+
+	slices.Sort(s.paths)
+
+	return s, nil
+}
+
+// Frame implements CameraSource.
+func (s *DirSource) Frame() image.Image {
+	path := s.paths[s.next]
+	s.next = (s.next + 1) % len(s.paths)
+
+	frame, err := loadPng(path)
+	if err != nil {
+		log.Printf("failed to load camera frame %s: %s", path, err)
+		return nil
+	}
+
+	return frame
+}
+
+var _ CameraSource = (*DirSource)(nil)
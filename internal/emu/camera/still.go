@@ -0,0 +1,40 @@
+package camera
+
+import (
+	"image"
+	"image/png"
+	"os"
+
+	. "github.com/indeedhat/gb-emulator/internal/emu/types"
+)
+
+// StillSource shows the camera the same image for every capture
+type StillSource struct {
+	frame image.Image
+}
+
+func NewStillSource(path string) (*StillSource, error) {
+	frame, err := loadPng(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StillSource{frame: frame}, nil
+}
+
+// Frame implements CameraSource.
+func (s *StillSource) Frame() image.Image {
+	return s.frame
+}
+
+func loadPng(path string) (image.Image, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	return png.Decode(fh)
+}
+
+var _ CameraSource = (*StillSource)(nil)
@@ -1,6 +1,7 @@
 package cart
 
 import (
+	"bytes"
 	"errors"
 	"io"
 	"log"
@@ -36,11 +37,54 @@ func Load(path string) (*Cartridge, error) {
 		return nil, err
 	}
 
+	if header := mmm01Header(data); header != nil {
+		c.header = header
+	}
+
 	c.initMbc(path, data)
 
 	return c, nil
 }
 
+// isMbc1Multicart attempts to detect MBC1M carts by looking for the header logo repeated at the
+// start of one of the games at each 256 KiB boundary
+func isMbc1Multicart(data []byte) bool {
+	if len(data) != 0x100000 {
+		return false
+	}
+
+	logo := data[0x0104:0x0134]
+	for offset := 0x40000; offset < len(data); offset += 0x40000 {
+		if bytes.Equal(logo, data[offset+0x0104:offset+0x0134]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mmm01Header looks for the MMM01 header in the menu at the end of the rom
+//
+// MMM01 carts boot from the last 32 KiB so the header at the start of the rom belongs to the
+// first game on the cart rather than the cart itself
+func mmm01Header(data []byte) *CartHeader {
+	if len(data) <= 0x8000 || len(data)%0x4000 != 0 {
+		return nil
+	}
+
+	header := &CartHeader{}
+	if err := header.Parse(data[len(data)-0x8000:]); err != nil {
+		return nil
+	}
+
+	switch header.CartType {
+	case CartTypeMmm01, CartTypeMMM01Ram, CartTypeMMM01RamBattery:
+		return header
+	}
+
+	return nil
+}
+
 func (c *Cartridge) LoadState(data []byte) {
 	c.data.LoadState(data)
 }
@@ -69,6 +113,10 @@ func (c *Cartridge) Mbc() MBC {
 	return c.data
 }
 
+func (c *Cartridge) Header() *CartHeader {
+	return c.header
+}
+
 func (c *Cartridge) Filepath() string {
 	return c.path
 }
@@ -79,16 +127,57 @@ func (c *Cartridge) initMbc(path string, data []byte) {
 	switch c.header.CartType {
 	case CartTypeRomOnly:
 		c.data = MBCNone(data)
+	case CartTypeRomRam, CartTypeRomRamBattery:
+		c.data, err = NewRomRam(path, data, c.header)
+		if err != nil {
+			log.Fatalf("failed to init ROM+RAM: %s", err)
+		}
+	case CartTypeMmm01, CartTypeMMM01Ram, CartTypeMMM01RamBattery:
+		c.data, err = NewMMM01(path, data, c.header)
+		if err != nil {
+			log.Fatalf("failed to init MMM01: %s", err)
+		}
 	case CartTypeMbc1, CartTypeMbc1Ram, CartTypeMbc1RamBattery:
-		c.data, err = NewMBC1(path, data, c.header)
+		c.data, err = NewMBC1(path, data, c.header, isMbc1Multicart(data))
 		if err != nil {
 			log.Fatalf("failed to init MBC1: %s", err)
 		}
+	case CartTypeMbc2, CartTypeMbc2RamBattery:
+		c.data, err = NewMBC2(path, data, c.header)
+		if err != nil {
+			log.Fatalf("failed to init MBC2: %s", err)
+		}
 	case CartTypeMbc3, CartTypeMbc3Ram, CartTypeMbc3RamBattery, CartTypeMbc3TimerBattery, CartTypeMbc3TimerRamBattery:
 		c.data, err = NewMBC3(path, data, c.header)
 		if err != nil {
 			log.Fatalf("failed to init MBC3: %s", err)
 		}
+	case CartTypeMbc5, CartTypeMbc5Ram, CartTypeMbc5RamBattery,
+		CartTypeMbc5Rumble, CartTypeMbc5RumbleRam, CartTypeMbc5RumbleRamBattery:
+		c.data, err = NewMBC5(path, data, c.header)
+		if err != nil {
+			log.Fatalf("failed to init MBC5: %s", err)
+		}
+	case CartTypeMbc7SensorRumbleRamBattery:
+		c.data, err = NewMBC7(path, data, c.header)
+		if err != nil {
+			log.Fatalf("failed to init MBC7: %s", err)
+		}
+	case CartTypePocketCamera:
+		c.data, err = NewPocketCamera(path, data, c.header)
+		if err != nil {
+			log.Fatalf("failed to init pocket camera: %s", err)
+		}
+	case CartTypeHuC1RamBattery:
+		c.data, err = NewHuC1(path, data, c.header)
+		if err != nil {
+			log.Fatalf("failed to init HuC1: %s", err)
+		}
+	case CartTypeHuC3:
+		c.data, err = NewHuC3(path, data, c.header)
+		if err != nil {
+			log.Fatalf("failed to init HuC3: %s", err)
+		}
 	default:
 		spew.Dump(c.header)
 		panic("mbc type not implemented")
@@ -127,8 +216,10 @@ type CartHeader struct {
 	EntryPoint [4]byte
 	// 0x0104 - 0x0133
 	Logo [48]byte
-	// 0x0134 - 0x0143
+	// 0x0134 - 0x0142
 	Title string
+	// 0x0143
+	CgbFlag byte
 	// 0x0144 - 0x0145
 	NewLicensee string
 	// 0x0146
@@ -159,6 +250,7 @@ func (h *CartHeader) Parse(data []byte) error {
 	copy(h.EntryPoint[:], data[0x0100:0x0103])
 	copy(h.Logo[:], data[0x0104:0x0133])
 	h.Title = string(data[0x0134:0x0143])
+	h.CgbFlag = data[0x0143]
 	h.NewLicensee = string(data[0x0144:0x0145])
 	h.SgbFlag = data[0x0146]
 	h.CartType = data[0x0147]
@@ -182,6 +274,11 @@ func (h *CartHeader) Parse(data []byte) error {
 	return nil
 }
 
+// IsCgb reports if the rom supports (0x80) or requires (0xC0) the gameboy color hardware
+func (h *CartHeader) IsCgb() bool {
+	return h.CgbFlag&0x80 == 0x80
+}
+
 func (h *CartHeader) RomBanks() uint16 {
 	switch h.RomSize {
 	case 0x00:
@@ -207,7 +304,7 @@ func (h *CartHeader) RomBanks() uint16 {
 }
 
 func (h *CartHeader) RamBanks() uint16 {
-	switch h.RomSize {
+	switch h.RamSize {
 	case 0x02:
 		return 1
 	case 0x03:
@@ -0,0 +1,201 @@
+package cart
+
+const (
+	eepromStateIdle = iota
+	eepromStateCommand
+	eepromStateWrite
+	eepromStateRead
+)
+
+// eeprom93lc56 emulates the 256 byte microwire serial eeprom used for saves on MBC7 carts
+//
+// it is organised as 128 16 bit words, each command is a start bit followed by a 2 bit opcode
+// and an 8 bit address clocked in msb first on the rising edge of clk while cs is held high
+type eeprom93lc56 struct {
+	data []byte
+
+	cs  bool
+	clk bool
+	di  bool
+	do  bool
+
+	writeEnabled bool
+	writeAll     bool
+
+	state uint8
+	shift uint16
+	bits  uint8
+	addr  uint8
+
+	changed bool
+}
+
+func newEeprom93lc56() *eeprom93lc56 {
+	e := &eeprom93lc56{
+		data: make([]byte, 0x100),
+		do:   true,
+	}
+
+	for i := range e.data {
+		e.data[i] = 0xFF
+	}
+
+	return e
+}
+
+// Read returns the current pin state, data out is in bit 0
+func (e *eeprom93lc56) Read() uint8 {
+	var value uint8
+	if e.cs {
+		value |= 0x80
+	}
+	if e.clk {
+		value |= 0x40
+	}
+	if e.di {
+		value |= 0x02
+	}
+	if e.do {
+		value |= 0x01
+	}
+
+	return value
+}
+
+// Write sets the pin state
+//
+// Bit 7: chip select
+// Bit 6: clock
+// Bit 1: data in
+func (e *eeprom93lc56) Write(value uint8) {
+	cs := value&0x80 == 0x80
+	clk := value&0x40 == 0x40
+	rising := clk && !e.clk
+
+	e.cs = cs
+	e.clk = clk
+	e.di = value&0x02 == 0x02
+
+	// NB: dropping cs aborts any partially clocked command
+	if !cs {
+		if e.state != eepromStateIdle {
+			e.state = eepromStateIdle
+			e.do = true
+		}
+		return
+	}
+
+	if rising {
+		e.clock()
+	}
+}
+
+func (e *eeprom93lc56) clock() {
+	var bit uint16
+	if e.di {
+		bit = 1
+	}
+
+	switch e.state {
+	case eepromStateIdle:
+		if bit == 1 {
+			e.state = eepromStateCommand
+			e.shift = 0
+			e.bits = 0
+		}
+
+	case eepromStateCommand:
+		e.shift = e.shift<<1 | bit
+		e.bits++
+
+		if e.bits == 10 {
+			e.execCommand(uint8(e.shift>>8)&0x03, uint8(e.shift))
+		}
+
+	case eepromStateWrite:
+		e.shift = e.shift<<1 | bit
+		e.bits++
+
+		if e.bits < 16 {
+			return
+		}
+
+		if e.writeEnabled {
+			if e.writeAll {
+				for i := range uint8(0x80) {
+					e.setWord(i, e.shift)
+				}
+			} else {
+				e.setWord(e.addr, e.shift)
+			}
+		}
+
+		e.state = eepromStateIdle
+		e.do = true
+
+	case eepromStateRead:
+		e.do = e.shift&0x8000 == 0x8000
+		e.shift <<= 1
+		e.bits++
+
+		// NB: holding cs high after a word keeps reading sequential addresses
+		if e.bits == 16 {
+			e.addr = (e.addr + 1) & 0x7F
+			e.shift = e.word(e.addr)
+			e.bits = 0
+		}
+	}
+}
+
+func (e *eeprom93lc56) execCommand(opcode, address uint8) {
+	e.addr = address & 0x7F
+	e.shift = 0
+	e.bits = 0
+	e.state = eepromStateIdle
+
+	switch opcode {
+	case 0b10: // READ
+		e.state = eepromStateRead
+		e.shift = e.word(e.addr)
+		// NB: a dummy 0 bit precedes the data
+		e.do = false
+
+	case 0b01: // WRITE
+		e.state = eepromStateWrite
+		e.writeAll = false
+
+	case 0b11: // ERASE
+		if e.writeEnabled {
+			e.setWord(e.addr, 0xFFFF)
+		}
+		e.do = true
+
+	case 0b00:
+		switch address >> 6 {
+		case 0b11: // EWEN
+			e.writeEnabled = true
+		case 0b00: // EWDS
+			e.writeEnabled = false
+		case 0b10: // ERAL
+			if e.writeEnabled {
+				for i := range uint8(0x80) {
+					e.setWord(i, 0xFFFF)
+				}
+			}
+			e.do = true
+		case 0b01: // WRAL
+			e.state = eepromStateWrite
+			e.writeAll = true
+		}
+	}
+}
+
+func (e *eeprom93lc56) word(addr uint8) uint16 {
+	return uint16(e.data[addr*2]) | uint16(e.data[addr*2+1])<<8
+}
+
+func (e *eeprom93lc56) setWord(addr uint8, value uint16) {
+	e.data[addr*2] = uint8(value)
+	e.data[addr*2+1] = uint8(value >> 8)
+	e.changed = true
+}
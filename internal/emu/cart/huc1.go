@@ -0,0 +1,199 @@
+package cart
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	. "github.com/indeedhat/gb-emulator/internal/emu/types"
+)
+
+type HuC1 struct {
+	path string
+
+	romBanks uint16
+	romData  []byte
+
+	ramBanks uint16
+	ramData  []byte
+
+	// registers
+	romBank uint8
+	ramBank uint8
+	irMode  bool
+
+	irLed   bool
+	irLedCh chan bool
+
+	hasBattery    bool
+	hasRamChanges bool
+}
+
+func NewHuC1(path string, data []byte, header *CartHeader) (*HuC1, error) {
+	m := &HuC1{
+		path:       path,
+		romBanks:   header.RomBanks(),
+		romData:    data,
+		ramBanks:   header.RamBanks(),
+		ramData:    make([]byte, 0x2000*uint32(header.RamBanks())),
+		romBank:    1,
+		irLedCh:    make(chan bool, 8),
+		hasBattery: CartTypeHuC1RamBattery == header.CartType,
+	}
+
+	if err := m.Load(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *HuC1) SaveState() []byte {
+	var buf bytes.Buffer
+
+	binary.Write(&buf, binary.BigEndian, int64(len(m.path)))
+	buf.WriteString(m.path)
+
+	binary.Write(&buf, binary.BigEndian, m.romBanks)
+
+	binary.Write(&buf, binary.BigEndian, m.ramBanks)
+	buf.Write(m.ramData)
+
+	binary.Write(&buf, binary.BigEndian, m.romBank)
+	binary.Write(&buf, binary.BigEndian, m.ramBank)
+	binary.Write(&buf, binary.BigEndian, m.irMode)
+	binary.Write(&buf, binary.BigEndian, m.irLed)
+	binary.Write(&buf, binary.BigEndian, m.hasBattery)
+
+	return buf.Bytes()
+}
+
+func (m *HuC1) LoadState(data []byte) {
+	r := bytes.NewReader(data)
+
+	var strlen int64
+	binary.Read(r, binary.BigEndian, &strlen)
+	buf := make([]byte, strlen)
+	r.Read(buf)
+	m.path = string(buf)
+
+	binary.Read(r, binary.BigEndian, &m.romBanks)
+
+	binary.Read(r, binary.BigEndian, &m.ramBanks)
+	r.Read(m.ramData)
+
+	binary.Read(r, binary.BigEndian, &m.romBank)
+	binary.Read(r, binary.BigEndian, &m.ramBank)
+	binary.Read(r, binary.BigEndian, &m.irMode)
+
+	var irLed bool
+	binary.Read(r, binary.BigEndian, &irLed)
+	m.setIrLed(irLed)
+
+	binary.Read(r, binary.BigEndian, &m.hasBattery)
+}
+
+func (m *HuC1) Read(address uint16) byte {
+	switch true {
+	case address < 0x4000:
+		return m.romData[address]
+
+	case address < 0x8000:
+		offset := uint32(uint16(m.romBank)%m.romBanks) * 0x4000
+		return m.romData[offset+uint32(address-0x4000)]
+
+	case address >= 0xA000 && address < 0xC000:
+		if m.irMode {
+			return 0xC0
+		}
+
+		if m.ramBanks == 0 {
+			return 0xFF
+		}
+
+		offset := uint32(uint16(m.ramBank)%m.ramBanks) * 0x2000
+		return m.ramData[offset+uint32(address-0xA000)]
+	}
+
+	panic("bad cart read")
+}
+
+func (m *HuC1) Write(address uint16, value byte) {
+	switch true {
+	case address < 0x2000:
+		// NB: HuC1 has no ram enable, any value other than 0x0E maps ram in
+		m.irMode = value&0x0F == 0x0E
+
+	case address < 0x4000:
+		m.romBank = value & 0x3F
+
+	case address < 0x6000:
+		m.ramBank = value & 0x03
+
+	case address >= 0xA000 && address < 0xC000:
+		if m.irMode {
+			m.setIrLed(value&0x01 == 0x01)
+			return
+		}
+
+		if m.ramBanks == 0 {
+			return
+		}
+
+		m.hasRamChanges = true
+
+		offset := uint32(uint16(m.ramBank)%m.ramBanks) * 0x2000
+		m.ramData[offset+uint32(address-0xA000)] = value
+	}
+}
+
+// IrLedCh implements InfraredEmitter.
+func (m *HuC1) IrLedCh() <-chan bool {
+	return m.irLedCh
+}
+
+func (m *HuC1) setIrLed(on bool) {
+	if on == m.irLed {
+		return
+	}
+
+	m.irLed = on
+
+	select {
+	case m.irLedCh <- on:
+	default:
+	}
+}
+
+// Load implements MBC.
+func (m *HuC1) Load() error {
+	if !m.hasBattery {
+		return nil
+	}
+
+	data, legacy, err := readSave(m.path)
+	if err != nil || data == nil {
+		return err
+	}
+
+	copy(m.ramData, data)
+
+	if legacy {
+		m.hasRamChanges = true
+		return migrateSave(m.path, m.Save)
+	}
+
+	return nil
+}
+
+// Save implements MBC.
+func (m *HuC1) Save() error {
+	if !m.hasBattery || !m.hasRamChanges {
+		return nil
+	}
+
+	m.hasRamChanges = false
+	return writeSave(m.path, m.ramData)
+}
+
+var _ MBC = (*HuC1)(nil)
+var _ InfraredEmitter = (*HuC1)(nil)
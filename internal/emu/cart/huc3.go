@@ -0,0 +1,330 @@
+package cart
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	. "github.com/indeedhat/gb-emulator/internal/emu/types"
+)
+
+// values written to 0x0000 - 0x1FFF to select what is mapped into 0xA000 - 0xBFFF
+const (
+	huc3ModeRamRead      = 0x0
+	huc3ModeRamWrite     = 0xA
+	huc3ModeRtcCommand   = 0xB
+	huc3ModeRtcResponse  = 0xC
+	huc3ModeRtcSemaphore = 0xD
+	huc3ModeIr           = 0xE
+)
+
+// commands written to the rtc in huc3ModeRtcCommand (bits 6-4 of the value)
+const (
+	huc3CmdRead       = 0x1
+	huc3CmdWrite      = 0x3
+	huc3CmdAddrLow    = 0x4
+	huc3CmdAddrHigh   = 0x5
+	huc3CmdExtended   = 0x6
+	huc3ExtLatchClock = 0x0
+	huc3ExtSetClock   = 0x1
+	huc3ExtStatus     = 0x2
+)
+
+const huc3RtcMemSize = 0x100
+
+type HuC3 struct {
+	path string
+
+	romBanks uint16
+	romData  []byte
+
+	ramBanks uint16
+	ramData  []byte
+
+	// registers
+	romBank uint8
+	ramBank uint8
+	mode    uint8
+
+	// rtc
+	//
+	// the clock itself is derived from the host clock, rtcBase is the unix time at which the
+	// cart would have read 0 days 0 minutes. rtcMem is the nibble addressed memory the game
+	// talks to, the current time is copied in and out of its first 6 nibbles by command
+	rtcBase    int64
+	rtcMem     []byte
+	rtcAddr    uint8
+	rtcCommand uint8
+	rtcResult  uint8
+
+	irLed   bool
+	irLedCh chan bool
+
+	hasRamChanges bool
+}
+
+func NewHuC3(path string, data []byte, header *CartHeader) (*HuC3, error) {
+	m := &HuC3{
+		path:     path,
+		romBanks: header.RomBanks(),
+		romData:  data,
+		ramBanks: header.RamBanks(),
+		ramData:  make([]byte, 0x2000*uint32(header.RamBanks())),
+		romBank:  1,
+		rtcBase:  time.Now().Unix(),
+		rtcMem:   make([]byte, huc3RtcMemSize),
+		irLedCh:  make(chan bool, 8),
+	}
+
+	if err := m.Load(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *HuC3) SaveState() []byte {
+	var buf bytes.Buffer
+
+	binary.Write(&buf, binary.BigEndian, int64(len(m.path)))
+	buf.WriteString(m.path)
+
+	binary.Write(&buf, binary.BigEndian, m.romBanks)
+
+	binary.Write(&buf, binary.BigEndian, m.ramBanks)
+	buf.Write(m.ramData)
+
+	binary.Write(&buf, binary.BigEndian, m.romBank)
+	binary.Write(&buf, binary.BigEndian, m.ramBank)
+	binary.Write(&buf, binary.BigEndian, m.mode)
+
+	binary.Write(&buf, binary.BigEndian, m.rtcBase)
+	buf.Write(m.rtcMem)
+	binary.Write(&buf, binary.BigEndian, m.rtcAddr)
+	binary.Write(&buf, binary.BigEndian, m.rtcCommand)
+	binary.Write(&buf, binary.BigEndian, m.rtcResult)
+
+	binary.Write(&buf, binary.BigEndian, m.irLed)
+
+	return buf.Bytes()
+}
+
+func (m *HuC3) LoadState(data []byte) {
+	r := bytes.NewReader(data)
+
+	var strlen int64
+	binary.Read(r, binary.BigEndian, &strlen)
+	buf := make([]byte, strlen)
+	r.Read(buf)
+	m.path = string(buf)
+
+	binary.Read(r, binary.BigEndian, &m.romBanks)
+
+	binary.Read(r, binary.BigEndian, &m.ramBanks)
+	r.Read(m.ramData)
+
+	binary.Read(r, binary.BigEndian, &m.romBank)
+	binary.Read(r, binary.BigEndian, &m.ramBank)
+	binary.Read(r, binary.BigEndian, &m.mode)
+
+	binary.Read(r, binary.BigEndian, &m.rtcBase)
+	r.Read(m.rtcMem)
+	binary.Read(r, binary.BigEndian, &m.rtcAddr)
+	binary.Read(r, binary.BigEndian, &m.rtcCommand)
+	binary.Read(r, binary.BigEndian, &m.rtcResult)
+
+	var irLed bool
+	binary.Read(r, binary.BigEndian, &irLed)
+	m.setIrLed(irLed)
+}
+
+func (m *HuC3) Read(address uint16) byte {
+	switch true {
+	case address < 0x4000:
+		return m.romData[address]
+
+	case address < 0x8000:
+		offset := uint32(uint16(m.romBank)%m.romBanks) * 0x4000
+		return m.romData[offset+uint32(address-0x4000)]
+
+	case address >= 0xA000 && address < 0xC000:
+		switch m.mode {
+		case huc3ModeRamRead, huc3ModeRamWrite:
+			if m.ramBanks == 0 {
+				return 0xFF
+			}
+
+			offset := uint32(uint16(m.ramBank)%m.ramBanks) * 0x2000
+			return m.ramData[offset+uint32(address-0xA000)]
+
+		case huc3ModeRtcResponse:
+			return 0x80 | m.rtcCommand<<4 | m.rtcResult
+
+		case huc3ModeRtcSemaphore:
+			// NB: commands complete instantly so the rtc always reports ready
+			return 0xFF
+
+		case huc3ModeIr:
+			return 0xC0
+		}
+
+		return 0xFF
+	}
+
+	panic("bad cart read")
+}
+
+func (m *HuC3) Write(address uint16, value byte) {
+	switch true {
+	case address < 0x2000:
+		m.mode = value & 0x0F
+
+	case address < 0x4000:
+		m.romBank = value & 0x7F
+
+	case address < 0x6000:
+		m.ramBank = value & 0x03
+
+	case address >= 0xA000 && address < 0xC000:
+		switch m.mode {
+		case huc3ModeRamWrite:
+			if m.ramBanks == 0 {
+				return
+			}
+
+			m.hasRamChanges = true
+
+			offset := uint32(uint16(m.ramBank)%m.ramBanks) * 0x2000
+			m.ramData[offset+uint32(address-0xA000)] = value
+
+		case huc3ModeRtcCommand:
+			m.execRtcCommand(value>>4&0x07, value&0x0F)
+
+		case huc3ModeIr:
+			m.setIrLed(value&0x01 == 0x01)
+		}
+	}
+}
+
+// IrLedCh implements InfraredEmitter.
+func (m *HuC3) IrLedCh() <-chan bool {
+	return m.irLedCh
+}
+
+func (m *HuC3) setIrLed(on bool) {
+	if on == m.irLed {
+		return
+	}
+
+	m.irLed = on
+
+	select {
+	case m.irLedCh <- on:
+	default:
+	}
+}
+
+func (m *HuC3) execRtcCommand(command, arg uint8) {
+	m.rtcCommand = command
+
+	switch command {
+	case huc3CmdRead:
+		m.rtcResult = m.rtcMem[m.rtcAddr]
+		m.rtcAddr++
+
+	case huc3CmdWrite:
+		m.rtcMem[m.rtcAddr] = arg
+		m.rtcAddr++
+		m.hasRamChanges = true
+
+	case huc3CmdAddrLow:
+		m.rtcAddr = m.rtcAddr&0xF0 | arg
+
+	case huc3CmdAddrHigh:
+		m.rtcAddr = m.rtcAddr&0x0F | arg<<4
+
+	case huc3CmdExtended:
+		switch arg {
+		case huc3ExtLatchClock:
+			m.latchClock()
+		case huc3ExtSetClock:
+			m.setClock()
+		case huc3ExtStatus:
+			m.rtcResult = 0x1
+		}
+	}
+}
+
+// latchClock copies the current time into the first 6 nibbles of rtc memory
+//
+// nibbles 0-2 hold the minute of the day and 3-5 the day counter, both least significant first
+func (m *HuC3) latchClock() {
+	minutes := (time.Now().Unix() - m.rtcBase) / 60
+	if minutes < 0 {
+		minutes = 0
+	}
+
+	minute := uint16(minutes % 1440)
+	day := uint16(minutes/1440) & 0xFFF
+
+	for i := range 3 {
+		m.rtcMem[i] = uint8(minute>>(i*4)) & 0x0F
+		m.rtcMem[i+3] = uint8(day>>(i*4)) & 0x0F
+	}
+}
+
+// setClock sets the current time from the first 6 nibbles of rtc memory
+func (m *HuC3) setClock() {
+	var minute, day int64
+	for i := range 3 {
+		minute |= int64(m.rtcMem[i]&0x0F) << (i * 4)
+		day |= int64(m.rtcMem[i+3]&0x0F) << (i * 4)
+	}
+
+	m.rtcBase = time.Now().Unix() - (day*1440+minute)*60
+	m.hasRamChanges = true
+}
+
+// Load implements MBC.
+//
+// the save file contains the cart ram followed by the rtc memory and the clock base
+func (m *HuC3) Load() error {
+	data, legacy, err := readSave(m.path)
+	if err != nil || data == nil {
+		return err
+	}
+
+	copy(m.ramData, data)
+
+	if len(data) >= len(m.ramData)+huc3RtcMemSize+8 {
+		footer := data[len(m.ramData):]
+		copy(m.rtcMem, footer[:huc3RtcMemSize])
+		m.rtcBase = int64(binary.BigEndian.Uint64(footer[huc3RtcMemSize:]))
+	}
+
+	if legacy {
+		m.hasRamChanges = true
+		return migrateSave(m.path, m.Save)
+	}
+
+	return nil
+}
+
+// Save implements MBC.
+func (m *HuC3) Save() error {
+	if !m.hasRamChanges {
+		return nil
+	}
+
+	m.hasRamChanges = false
+
+	data := make([]byte, 0, len(m.ramData)+huc3RtcMemSize+8)
+	data = append(data, m.ramData...)
+	data = append(data, m.rtcMem...)
+	data = binary.BigEndian.AppendUint64(data, uint64(m.rtcBase))
+
+	return writeSave(m.path, data)
+}
+
+var _ MBC = (*HuC3)(nil)
+var _ InfraredEmitter = (*HuC3)(nil)
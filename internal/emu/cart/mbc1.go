@@ -3,9 +3,6 @@ package cart
 import (
 	"bytes"
 	"encoding/binary"
-	"errors"
-	"io/fs"
-	"os"
 
 	. "github.com/indeedhat/gb-emulator/internal/emu/types"
 )
@@ -21,22 +18,28 @@ type MBC1 struct {
 	ramEnabled bool
 
 	// registers
-	romBank  uint8
-	romBank2 uint8
-	ramBank  uint8
-	mode     uint8
+	romBank uint8
+	// bank2 is the 2 bit secondary bank register, it always supplies the upper rom bank bits and
+	// selects the ram bank in mode 1
+	bank2 uint8
+	mode  uint8
 
-	hasBattery bool
+	hasBattery    bool
+	hasRamChanges bool
+
+	// MBC1M multicarts only wire up 4 bits of the rom bank register
+	multicart bool
 }
 
-func NewMBC1(path string, data []byte, header *CartHeader) (*MBC1, error) {
+func NewMBC1(path string, data []byte, header *CartHeader, multicart bool) (*MBC1, error) {
 	m := &MBC1{
 		path:       path,
-		romBanks:   header.RomBanks(),
+		romBanks:   uint16(len(data) / 0x4000),
 		romData:    data,
 		ramBanks:   header.RamBanks(),
 		ramData:    make([]byte, 0x2000*uint32(header.RamBanks())),
 		hasBattery: CartTypeMbc1RamBattery == header.CartType,
+		multicart:  multicart,
 	}
 
 	if err := m.Load(); err != nil {
@@ -59,10 +62,10 @@ func (m *MBC1) SaveState() []byte {
 	binary.Write(&buf, binary.BigEndian, m.ramEnabled)
 
 	binary.Write(&buf, binary.BigEndian, m.romBank)
-	binary.Write(&buf, binary.BigEndian, m.romBank2)
-	binary.Write(&buf, binary.BigEndian, m.ramBank)
+	binary.Write(&buf, binary.BigEndian, m.bank2)
 	binary.Write(&buf, binary.BigEndian, m.mode)
 	binary.Write(&buf, binary.BigEndian, m.hasBattery)
+	binary.Write(&buf, binary.BigEndian, m.multicart)
 
 	return buf.Bytes()
 }
@@ -83,18 +86,18 @@ func (m *MBC1) LoadState(data []byte) {
 	binary.Read(r, binary.BigEndian, &m.ramEnabled)
 
 	binary.Read(r, binary.BigEndian, &m.romBank)
-	binary.Read(r, binary.BigEndian, &m.romBank2)
-	binary.Read(r, binary.BigEndian, &m.ramBank)
+	binary.Read(r, binary.BigEndian, &m.bank2)
 	binary.Read(r, binary.BigEndian, &m.mode)
 	binary.Read(r, binary.BigEndian, &m.hasBattery)
+	binary.Read(r, binary.BigEndian, &m.multicart)
 }
 
 func (m *MBC1) Read(address uint16) byte {
 	var offset uint32
 	switch true {
 	case address < 0x4000:
-		if m.mode == 1 && m.romBanks >= 32 {
-			offset = uint32(m.romBank2) * 0x4000
+		if m.mode == 1 {
+			offset = m.bankOffset(uint32(m.bank2) << m.bankShift())
 		}
 
 		return m.romData[offset+uint32(address)]
@@ -103,16 +106,21 @@ func (m *MBC1) Read(address uint16) byte {
 		if m.romBank == 0 {
 			m.romBank = 1
 		}
-		offset = uint32(m.romBank) * 0x4000
+
+		bank := uint32(m.romBank)
+		if m.multicart {
+			bank &= 0x0F
+		}
+
+		offset = m.bankOffset(bank | uint32(m.bank2)<<m.bankShift())
 		return m.romData[offset+uint32(address-0x4000)]
 
 	case address <= 0xC000:
-		if !m.ramEnabled {
+		if !m.ramEnabled || m.ramBanks == 0 {
 			return 0xFF
 		}
 
-		offset = uint32(m.ramBank) * 0x2000
-		return m.ramData[offset+uint32(address-0xA000)]
+		return m.ramData[m.ramOffset()+uint32(address-0xA000)]
 	}
 
 	panic("bad cart read")
@@ -133,14 +141,10 @@ func (m *MBC1) Write(address uint16, value byte) {
 
 		m.romBank = value
 
-	case address <= 0x6000:
-		if m.ramBanks > 0 {
-			m.ramBank = (value & 0b11)
-		} else {
-			m.romBank2 = value & 0b11
-		}
+	case address < 0x6000:
+		m.bank2 = value & 0b11
 
-	case address <= 0x8000:
+	case address < 0x8000:
 		m.mode = value & 0x1
 
 	case address <= 0xC000:
@@ -148,24 +152,49 @@ func (m *MBC1) Write(address uint16, value byte) {
 			return
 		}
 
-		offset := uint32(m.ramBank) * 0x2000
-		m.ramData[offset+uint32(address-0xA000)] = value
+		m.hasRamChanges = true
+		m.ramData[m.ramOffset()+uint32(address-0xA000)] = value
 	}
 }
 
+// bankShift is the position of the upper rom bank bits (bank2) in the full bank number
+func (m *MBC1) bankShift() uint8 {
+	if m.multicart {
+		return 4
+	}
+
+	return 5
+}
+
+func (m *MBC1) bankOffset(bank uint32) uint32 {
+	return bank % uint32(m.romBanks) * 0x4000
+}
+
+// ramOffset is the start of the selected ram bank, bank2 is only used for ram in mode 1
+func (m *MBC1) ramOffset() uint32 {
+	if m.mode == 0 {
+		return 0
+	}
+
+	return uint32(uint16(m.bank2)%m.ramBanks) * 0x2000
+}
+
 // Load implements MBC.
 func (m *MBC1) Load() error {
 	if !m.hasBattery {
 		return nil
 	}
 
-	data, err := os.ReadFile(m.path + ".gbsav")
-	if !errors.Is(err, fs.ErrNotExist) {
+	data, legacy, err := readSave(m.path)
+	if err != nil || data == nil {
 		return err
 	}
 
-	if data != nil {
-		m.ramData = data
+	copy(m.ramData, data)
+
+	if legacy {
+		m.hasRamChanges = true
+		return migrateSave(m.path, m.Save)
 	}
 
 	return nil
@@ -173,11 +202,12 @@ func (m *MBC1) Load() error {
 
 // Save implements MBC.
 func (m *MBC1) Save() error {
-	if !m.hasBattery {
+	if !m.hasBattery || !m.hasRamChanges {
 		return nil
 	}
 
-	return os.WriteFile(m.path+".gbsav", m.ramData, 0644)
+	m.hasRamChanges = false
+	return writeSave(m.path, m.ramData)
 }
 
 var _ MBC = (*MBC1)(nil)
@@ -0,0 +1,106 @@
+package cart
+
+import "testing"
+
+// newTestRom creates a rom where the first byte of each bank holds the bank number
+func newTestRom(banks int) []byte {
+	data := make([]byte, banks*0x4000)
+	for bank := range banks {
+		data[bank*0x4000] = uint8(bank)
+	}
+
+	return data
+}
+
+func TestMBC1Banking(t *testing.T) {
+	cases := []struct {
+		name     string
+		romBanks int
+		ramSize  byte
+		writes   [][2]uint16
+		address  uint16
+		want     uint8
+	}{
+		{
+			name:     "upper rom bank bits with ram",
+			romBanks: 128,
+			ramSize:  0x03,
+			writes:   [][2]uint16{{0x2000, 0x01}, {0x4000, 0x02}},
+			address:  0x4000,
+			want:     0x41,
+		},
+		{
+			name:     "upper rom bank bits in mode 1",
+			romBanks: 128,
+			ramSize:  0x03,
+			writes:   [][2]uint16{{0x6000, 0x01}, {0x2000, 0x05}, {0x4000, 0x01}},
+			address:  0x4000,
+			want:     0x25,
+		},
+		{
+			name:     "bank 0 area uses the upper bits in mode 1",
+			romBanks: 128,
+			ramSize:  0x03,
+			writes:   [][2]uint16{{0x6000, 0x01}, {0x4000, 0x03}},
+			address:  0x0000,
+			want:     0x60,
+		},
+		{
+			name:     "bank 0 area ignores the upper bits in mode 0",
+			romBanks: 128,
+			ramSize:  0x03,
+			writes:   [][2]uint16{{0x4000, 0x03}},
+			address:  0x0000,
+			want:     0x00,
+		},
+		{
+			name:     "rom bank wraps to the rom size",
+			romBanks: 8,
+			writes:   [][2]uint16{{0x2000, 0x09}},
+			address:  0x4000,
+			want:     0x01,
+		},
+		{
+			name:     "ram bank 0 is used in mode 0",
+			romBanks: 4,
+			ramSize:  0x03,
+			writes:   [][2]uint16{{0x0000, 0x0A}, {0x4000, 0x02}, {0xA000, 0x42}, {0x4000, 0x00}, {0x6000, 0x01}},
+			address:  0xA000,
+			want:     0x42,
+		},
+		{
+			name:     "ram bank is selected in mode 1",
+			romBanks: 4,
+			ramSize:  0x03,
+			writes:   [][2]uint16{{0x0000, 0x0A}, {0x6000, 0x01}, {0x4000, 0x02}, {0xA000, 0x42}, {0x4000, 0x00}},
+			address:  0xA000,
+			want:     0x00,
+		},
+		{
+			name:     "ram bank wraps on a single bank cart",
+			romBanks: 4,
+			ramSize:  0x02,
+			writes:   [][2]uint16{{0x0000, 0x0A}, {0x6000, 0x01}, {0x4000, 0x03}, {0xA000, 0x42}, {0x4000, 0x00}},
+			address:  0xA000,
+			want:     0x42,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			header := &CartHeader{CartType: CartTypeMbc1Ram, RamSize: tc.ramSize}
+			m, err := NewMBC1("", newTestRom(tc.romBanks), header, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, w := range tc.writes {
+				m.Write(w[0], uint8(w[1]))
+			}
+
+			if got := m.Read(tc.address); got != tc.want {
+				t.Fatalf("read 0x%04X = 0x%02X, want 0x%02X", tc.address, got, tc.want)
+			}
+		})
+	}
+}
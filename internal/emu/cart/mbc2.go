@@ -0,0 +1,156 @@
+package cart
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	. "github.com/indeedhat/gb-emulator/internal/emu/types"
+)
+
+type MBC2 struct {
+	path string
+
+	romBanks uint16
+	romData  []byte
+
+	// 512 x 4 bit built in ram, only the lower nibble of each byte is used
+	ramData    []byte
+	ramEnabled bool
+
+	// registers
+	romBank uint8
+
+	hasBattery    bool
+	hasRamChanges bool
+}
+
+func NewMBC2(path string, data []byte, header *CartHeader) (*MBC2, error) {
+	m := &MBC2{
+		path:       path,
+		romBanks:   header.RomBanks(),
+		romData:    data,
+		ramData:    make([]byte, 0x200),
+		romBank:    1,
+		hasBattery: CartTypeMbc2RamBattery == header.CartType,
+	}
+
+	if err := m.Load(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *MBC2) SaveState() []byte {
+	var buf bytes.Buffer
+
+	binary.Write(&buf, binary.BigEndian, int64(len(m.path)))
+	buf.WriteString(m.path)
+
+	binary.Write(&buf, binary.BigEndian, m.romBanks)
+
+	buf.Write(m.ramData)
+	binary.Write(&buf, binary.BigEndian, m.ramEnabled)
+
+	binary.Write(&buf, binary.BigEndian, m.romBank)
+	binary.Write(&buf, binary.BigEndian, m.hasBattery)
+
+	return buf.Bytes()
+}
+
+func (m *MBC2) LoadState(data []byte) {
+	r := bytes.NewReader(data)
+
+	var strlen int64
+	binary.Read(r, binary.BigEndian, &strlen)
+	buf := make([]byte, strlen)
+	r.Read(buf)
+	m.path = string(buf)
+
+	binary.Read(r, binary.BigEndian, &m.romBanks)
+
+	r.Read(m.ramData)
+	binary.Read(r, binary.BigEndian, &m.ramEnabled)
+
+	binary.Read(r, binary.BigEndian, &m.romBank)
+	binary.Read(r, binary.BigEndian, &m.hasBattery)
+}
+
+func (m *MBC2) Read(address uint16) byte {
+	switch true {
+	case address < 0x4000:
+		return m.romData[address]
+
+	case address < 0x8000:
+		offset := uint32(uint16(m.romBank)%m.romBanks) * 0x4000
+		return m.romData[offset+uint32(address-0x4000)]
+
+	case address >= 0xA000 && address < 0xC000:
+		if !m.ramEnabled {
+			return 0xFF
+		}
+
+		// NB: the 512 bytes of ram are echoed across the whole of 0xA000 - 0xBFFF
+		//     and the upper nibble is not connected so always reads as 1s
+		return m.ramData[address&0x1FF] | 0xF0
+	}
+
+	panic("bad cart read")
+}
+
+func (m *MBC2) Write(address uint16, value byte) {
+	switch true {
+	case address < 0x4000:
+		// NB: bit 8 of the address selects between the ram enable and rom bank registers
+		if address&0x100 == 0 {
+			m.ramEnabled = value&0x0F == 0x0A
+			return
+		}
+
+		m.romBank = value & 0x0F
+		if m.romBank == 0 {
+			m.romBank = 1
+		}
+
+	case address >= 0xA000 && address < 0xC000:
+		if !m.ramEnabled {
+			return
+		}
+
+		m.hasRamChanges = true
+		m.ramData[address&0x1FF] = value & 0x0F
+	}
+}
+
+// Load implements MBC.
+func (m *MBC2) Load() error {
+	if !m.hasBattery {
+		return nil
+	}
+
+	data, legacy, err := readSave(m.path)
+	if err != nil || data == nil {
+		return err
+	}
+
+	copy(m.ramData, data)
+
+	if legacy {
+		m.hasRamChanges = true
+		return migrateSave(m.path, m.Save)
+	}
+
+	return nil
+}
+
+// Save implements MBC.
+func (m *MBC2) Save() error {
+	if !m.hasBattery || !m.hasRamChanges {
+		return nil
+	}
+
+	m.hasRamChanges = false
+	return writeSave(m.path, m.ramData)
+}
+
+var _ MBC = (*MBC2)(nil)
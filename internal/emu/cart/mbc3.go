@@ -3,13 +3,34 @@ package cart
 import (
 	"bytes"
 	"encoding/binary"
-	"errors"
-	"io/fs"
-	"os"
+	"time"
 
+	"github.com/indeedhat/gb-emulator/internal/emu/config"
 	. "github.com/indeedhat/gb-emulator/internal/emu/types"
 )
 
+// indexes of the rtc registers, selected by writing 0x08 - 0x0C to the ram bank register
+const (
+	RtcSeconds = iota
+	RtcMinutes
+	RtcHours
+	RtcDaysLow
+	RtcDaysHigh
+)
+
+// RtcDaysHigh flags
+const (
+	RtcDayHigh  = uint8(1)
+	RtcHalt     = uint8(1) << 6
+	RtcDayCarry = uint8(1) << 7
+)
+
+// size of the rtc footer appended to the ram in battery saves
+const rtcFooterSize = 48
+
+// bits that are not connected in each rtc register, they always read back as 1s
+var rtcUnusedBits = [5]uint8{0xC0, 0xC0, 0xE0, 0x00, 0x3E}
+
 type MBC3 struct {
 	path string
 
@@ -22,7 +43,8 @@ type MBC3 struct {
 
 	rtcData        []byte
 	rtcLatchedData []byte
-	rtcLatched     bool
+	rtcLatchPrimed bool
+	rtcCycles      uint32
 
 	// registers
 	romBank     uint8
@@ -31,6 +53,7 @@ type MBC3 struct {
 	mode        uint8
 
 	hasBattery    bool
+	hasRtc        bool
 	hasRamChanges bool
 }
 
@@ -46,6 +69,8 @@ func NewMBC3(path string, data []byte, header *CartHeader) (*MBC3, error) {
 		hasBattery: CartTypeMbc3RamBattery == header.CartType ||
 			CartTypeMbc3TimerBattery == header.CartType ||
 			CartTypeMbc3TimerRamBattery == header.CartType,
+		hasRtc: CartTypeMbc3TimerBattery == header.CartType ||
+			CartTypeMbc3TimerRamBattery == header.CartType,
 	}
 
 	if err := m.Load(); err != nil {
@@ -69,13 +94,15 @@ func (m *MBC3) SaveState() []byte {
 
 	buf.Write(m.rtcData)
 	buf.Write(m.rtcLatchedData)
-	binary.Write(&buf, binary.BigEndian, m.rtcLatched)
+	binary.Write(&buf, binary.BigEndian, m.rtcLatchPrimed)
 
 	binary.Write(&buf, binary.BigEndian, m.romBank)
 	binary.Write(&buf, binary.BigEndian, m.ramBank)
 	binary.Write(&buf, binary.BigEndian, m.rtcRegister)
 	binary.Write(&buf, binary.BigEndian, m.mode)
 	binary.Write(&buf, binary.BigEndian, m.hasBattery)
+	binary.Write(&buf, binary.BigEndian, m.hasRtc)
+	binary.Write(&buf, binary.BigEndian, m.rtcCycles)
 
 	return buf.Bytes()
 }
@@ -97,13 +124,15 @@ func (m *MBC3) LoadState(data []byte) {
 
 	r.Read(m.rtcData)
 	r.Read(m.rtcLatchedData)
-	binary.Read(r, binary.BigEndian, &m.rtcLatched)
+	binary.Read(r, binary.BigEndian, &m.rtcLatchPrimed)
 
 	binary.Read(r, binary.BigEndian, &m.romBank)
 	binary.Read(r, binary.BigEndian, &m.ramBank)
 	binary.Read(r, binary.BigEndian, &m.rtcRegister)
 	binary.Read(r, binary.BigEndian, &m.mode)
 	binary.Read(r, binary.BigEndian, &m.hasBattery)
+	binary.Read(r, binary.BigEndian, &m.hasRtc)
+	binary.Read(r, binary.BigEndian, &m.rtcCycles)
 }
 
 func (m *MBC3) Read(address uint16) byte {
@@ -116,21 +145,26 @@ func (m *MBC3) Read(address uint16) byte {
 		offset = uint32(m.romBank) * 0x4000
 		return m.romData[offset+uint32(address-0x4000)]
 
-	case address <= 0xC000:
+	case address >= 0xA000 && address < 0xC000:
 		if !m.ramRtcEnabled {
 			return 0xFF
 		}
 
 		if m.ramBank <= 0x3 {
-			offset = uint32(m.ramBank) * 0x2000
+			if m.ramBanks == 0 {
+				return 0xFF
+			}
+
+			offset = uint32(uint16(m.ramBank)%m.ramBanks) * 0x2000
 			return m.ramData[offset+uint32(address-0xA000)]
 		}
 
-		if m.rtcLatched {
-			return m.rtcLatchedData[m.rtcRegister]
+		if !m.hasRtc {
+			return 0xFF
 		}
 
-		return m.rtcData[m.rtcRegister]
+		// NB: the rtc registers can only be read through the latch
+		return m.rtcLatchedData[m.rtcRegister] | rtcUnusedBits[m.rtcRegister]
 	}
 
 	panic("bad cart read")
@@ -139,66 +173,135 @@ func (m *MBC3) Read(address uint16) byte {
 func (m *MBC3) Write(address uint16, value byte) {
 	switch true {
 	case address < 0x2000:
-		m.ramRtcEnabled = value&0x0A == 0x0A
+		m.ramRtcEnabled = value&0x0F == 0x0A
 
 	case address < 0x4000:
 		value &= 0b01111111
 		m.romBank = value
 
-	case address <= 0x6000:
-		if !m.ramRtcEnabled {
-			return
-		}
-
+	case address < 0x6000:
 		if value <= 0x3 {
 			m.ramBank = value
 		} else if value >= 0x8 && value <= 0xC {
+			m.ramBank = value
 			m.rtcRegister = value - 0x8
 		}
 
-	case address <= 0x8000:
-		if value == 0x1 && !m.rtcLatched {
-			m.rtcLatched = true
+	case address < 0x8000:
+		// NB: writing 0 then 1 copies the live clock into the latch
+		if value == 0x1 && m.rtcLatchPrimed {
 			copy(m.rtcLatchedData, m.rtcData)
-		} else if value == 0x0 {
-			m.rtcLatched = false
+		}
+		m.rtcLatchPrimed = value == 0x0
+
+	case address >= 0xA000 && address < 0xC000:
+		if !m.ramRtcEnabled {
+			return
+		}
+
+		if m.ramBank > 0x3 {
+			m.writeRtc(value)
+			return
 		}
 
-	case address <= 0xC000:
 		if m.ramBanks == 0 {
 			return
 		}
 
 		m.hasRamChanges = true
 
-		offset := uint32(m.ramBank) * 0x2000
+		offset := uint32(uint16(m.ramBank)%m.ramBanks) * 0x2000
 		m.ramData[offset+uint32(address-0xA000)] = value
 	}
 }
 
+func (m *MBC3) writeRtc(value uint8) {
+	if !m.hasRtc {
+		return
+	}
+
+	value &^= rtcUnusedBits[m.rtcRegister]
+
+	// NB: writing the seconds register resets the sub second divider
+	if m.rtcRegister == RtcSeconds {
+		m.rtcCycles = 0
+	}
+
+	m.rtcData[m.rtcRegister] = value
+	m.rtcLatchedData[m.rtcRegister] = value
+	m.hasRamChanges = true
+}
+
 // Load implements MBC.
+//
+// the save file contains the raw cart ram, carts with an rtc have the 48 byte footer used by
+// most other emulators appended. it holds the live and latched rtc registers as 32 bit little
+// endian values followed by the 64 bit unix time the file was written so the time spent offline
+// can be applied to the clock
 func (m *MBC3) Load() error {
 	if !m.hasBattery {
 		return nil
 	}
 
-	data, err := os.ReadFile(m.path + ".gbsav")
-	if !errors.Is(err, fs.ErrNotExist) {
+	data, legacy, err := readSave(m.path)
+	if err != nil || data == nil {
 		return err
 	}
 
-	if err != nil {
-		panic(err)
+	if legacy {
+		m.loadLegacySave(data)
+		m.hasRamChanges = true
+		return migrateSave(m.path, m.Save)
 	}
 
-	if data != nil {
-		m.rtcData = data[:5]
-		m.ramData = data[5:]
+	copy(m.ramData, data)
+
+	// NB: some emulators write a 44 byte footer with a 32 bit timestamp
+	footer := data[min(len(data), len(m.ramData)):]
+	if !m.hasRtc || len(footer) < rtcFooterSize-4 {
+		return nil
 	}
 
+	for i := range len(m.rtcData) {
+		m.rtcData[i] = uint8(binary.LittleEndian.Uint32(footer[i*4:]))
+		m.rtcLatchedData[i] = uint8(binary.LittleEndian.Uint32(footer[20+i*4:]))
+	}
+
+	var saved int64
+	if len(footer) >= rtcFooterSize {
+		saved = int64(binary.LittleEndian.Uint64(footer[40:]))
+	} else {
+		saved = int64(binary.LittleEndian.Uint32(footer[40:]))
+	}
+
+	m.applyOfflineTime(saved)
+
 	return nil
 }
 
+// loadLegacySave reads the old .gbsav format
+//
+// it held the rtc registers, followed by the cart ram and a big endian unix timestamp
+func (m *MBC3) loadLegacySave(data []byte) {
+	if len(data) < len(m.rtcData) {
+		return
+	}
+
+	copy(m.rtcData, data)
+	copy(m.ramData, data[len(m.rtcData):])
+	copy(m.rtcLatchedData, m.rtcData)
+
+	if len(data) >= len(m.rtcData)+len(m.ramData)+8 {
+		m.applyOfflineTime(int64(binary.BigEndian.Uint64(data[len(m.rtcData)+len(m.ramData):])))
+	}
+}
+
+func (m *MBC3) applyOfflineTime(saved int64) {
+	if elapsed := time.Now().Unix() - saved; elapsed > 0 {
+		m.advanceRtc(uint64(elapsed))
+	}
+}
+
 // Save implements MBC.
 func (m *MBC3) Save() error {
 	if !m.hasBattery || !m.hasRamChanges {
@@ -206,25 +309,120 @@ func (m *MBC3) Save() error {
 	}
 
 	m.hasRamChanges = false
-	return os.WriteFile(m.path+".gbsav", append(m.rtcData, m.ramData...), 0644)
+
+	if !m.hasRtc {
+		return writeSave(m.path, m.ramData)
+	}
+
+	data := make([]byte, 0, len(m.ramData)+rtcFooterSize)
+	data = append(data, m.ramData...)
+	for _, value := range m.rtcData {
+		data = binary.LittleEndian.AppendUint32(data, uint32(value))
+	}
+	for _, value := range m.rtcLatchedData {
+		data = binary.LittleEndian.AppendUint32(data, uint32(value))
+	}
+	data = binary.LittleEndian.AppendUint64(data, uint64(time.Now().Unix()))
+
+	return writeSave(m.path, data)
 }
 
+// Tick implements Ticker.
 func (m *MBC3) Tick() {
-	m.rtcData[0]++
-	if m.rtcData[0] >= 60 {
-		m.rtcData[0] = 0
-		m.rtcData[1]++
+	if !m.hasRtc || m.rtcHalted() {
+		return
+	}
+
+	m.rtcCycles++
+	if m.rtcCycles < config.CpuClockSpeed/4 {
+		return
+	}
+
+	m.rtcCycles = 0
+	m.tickRtc()
+}
+
+func (m *MBC3) rtcHalted() bool {
+	return m.rtcData[RtcDaysHigh]&RtcHalt == RtcHalt
+}
+
+// tickRtc advances the clock by a single second
+//
+// each counter only carries into the next when it hits its natural limit, counters that have
+// been set out of range by the game count up to their bit width before wrapping to 0
+func (m *MBC3) tickRtc() {
+	m.rtcData[RtcSeconds] = (m.rtcData[RtcSeconds] + 1) & 0x3F
+	if m.rtcData[RtcSeconds] != 60 {
+		return
+	}
+	m.rtcData[RtcSeconds] = 0
+
+	m.rtcData[RtcMinutes] = (m.rtcData[RtcMinutes] + 1) & 0x3F
+	if m.rtcData[RtcMinutes] != 60 {
+		return
+	}
+	m.rtcData[RtcMinutes] = 0
+
+	m.rtcData[RtcHours] = (m.rtcData[RtcHours] + 1) & 0x1F
+	if m.rtcData[RtcHours] != 24 {
+		return
+	}
+	m.rtcData[RtcHours] = 0
+
+	m.rtcData[RtcDaysLow]++
+	if m.rtcData[RtcDaysLow] != 0 {
+		return
 	}
 
-	if m.rtcData[1] >= 60 {
-		m.rtcData[1] = 0
-		m.rtcData[2]++
+	if m.rtcData[RtcDaysHigh]&RtcDayHigh == 0 {
+		m.rtcData[RtcDaysHigh] |= RtcDayHigh
+		return
 	}
 
-	if m.rtcData[2] >= 24 {
-		m.rtcData[2] = 0
-		m.rtcData[3]++
+	m.rtcData[RtcDaysHigh] &^= RtcDayHigh
+	m.rtcData[RtcDaysHigh] |= RtcDayCarry
+}
+
+// advanceRtc moves the clock forward by the given number of seconds
+func (m *MBC3) advanceRtc(seconds uint64) {
+	if !m.hasRtc || m.rtcHalted() {
+		return
 	}
+
+	// NB: step through any out of range values one second at a time so they wrap the same
+	//     way they would have if the emulator was running
+	for seconds > 0 && !m.rtcInRange() {
+		m.tickRtc()
+		seconds--
+	}
+
+	if seconds == 0 {
+		return
+	}
+
+	days := uint64(m.rtcData[RtcDaysLow]) | uint64(m.rtcData[RtcDaysHigh]&RtcDayHigh)<<8
+	total := days*86400 +
+		uint64(m.rtcData[RtcHours])*3600 +
+		uint64(m.rtcData[RtcMinutes])*60 +
+		uint64(m.rtcData[RtcSeconds]) +
+		seconds
+
+	days = total / 86400
+	if days >= 512 {
+		m.rtcData[RtcDaysHigh] |= RtcDayCarry
+		days %= 512
+	}
+
+	m.rtcData[RtcSeconds] = uint8(total % 60)
+	m.rtcData[RtcMinutes] = uint8(total / 60 % 60)
+	m.rtcData[RtcHours] = uint8(total / 3600 % 24)
+	m.rtcData[RtcDaysLow] = uint8(days)
+	m.rtcData[RtcDaysHigh] = m.rtcData[RtcDaysHigh]&^RtcDayHigh | uint8(days>>8)&RtcDayHigh
+}
+
+func (m *MBC3) rtcInRange() bool {
+	return m.rtcData[RtcSeconds] < 60 && m.rtcData[RtcMinutes] < 60 && m.rtcData[RtcHours] < 24
 }
 
 var _ MBC = (*MBC3)(nil)
+var _ Ticker = (*MBC3)(nil)
@@ -0,0 +1,135 @@
+package cart
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/indeedhat/gb-emulator/internal/emu/config"
+)
+
+// newTestMBC3 creates an MBC3 with an rtc and a single ram bank, its saves go in a temp dir
+func newTestMBC3(t *testing.T, cartType byte) (*MBC3, string) {
+	t.Helper()
+
+	romPath := filepath.Join(t.TempDir(), "test.gb")
+	header := &CartHeader{CartType: cartType, RamSize: 0x02}
+
+	m, err := NewMBC3(romPath, newTestRom(4), header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m, romPath
+}
+
+func TestMBC3Rtc(t *testing.T) {
+	cases := []struct {
+		name string
+		rtc  []uint8
+		run  func(m *MBC3)
+		want []uint8
+	}{
+		{
+			name: "one second",
+			rtc:  []uint8{0, 0, 0, 0, 0},
+			run:  func(m *MBC3) { m.tickRtc() },
+			want: []uint8{1, 0, 0, 0, 0},
+		},
+		{
+			name: "day carry after 511 days",
+			rtc:  []uint8{59, 59, 23, 0xFF, RtcDayHigh},
+			run:  func(m *MBC3) { m.tickRtc() },
+			want: []uint8{0, 0, 0, 0, RtcDayCarry},
+		},
+		{
+			name: "day carry is sticky",
+			rtc:  []uint8{59, 59, 23, 0xFF, RtcDayHigh | RtcDayCarry},
+			run:  func(m *MBC3) { m.tickRtc() },
+			want: []uint8{0, 0, 0, 0, RtcDayCarry},
+		},
+		{
+			name: "out of range seconds wrap without a carry",
+			rtc:  []uint8{63, 10, 0, 0, 0},
+			run:  func(m *MBC3) { m.tickRtc() },
+			want: []uint8{0, 10, 0, 0, 0},
+		},
+		{
+			name: "out of range hours wrap without a carry",
+			rtc:  []uint8{59, 59, 31, 0, 0},
+			run:  func(m *MBC3) { m.tickRtc() },
+			want: []uint8{0, 0, 0, 0, 0},
+		},
+		{
+			name: "a second of cycles ticks the clock",
+			rtc:  []uint8{0, 0, 0, 0, 0},
+			run: func(m *MBC3) {
+				for range config.CpuClockSpeed / 4 {
+					m.Tick()
+				}
+			},
+			want: []uint8{1, 0, 0, 0, 0},
+		},
+		{
+			name: "halted clock does not count cycles",
+			rtc:  []uint8{0, 0, 0, 0, RtcHalt},
+			run: func(m *MBC3) {
+				for range config.CpuClockSpeed / 4 {
+					m.Tick()
+				}
+			},
+			want: []uint8{0, 0, 0, 0, RtcHalt},
+		},
+		{
+			name: "halted clock does not apply offline time",
+			rtc:  []uint8{0, 0, 0, 0, RtcHalt},
+			run:  func(m *MBC3) { m.advanceRtc(3600) },
+			want: []uint8{0, 0, 0, 0, RtcHalt},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, _ := newTestMBC3(t, CartTypeMbc3TimerRamBattery)
+			copy(m.rtcData, tc.rtc)
+
+			tc.run(m)
+
+			if !slices.Equal(m.rtcData, tc.want) {
+				t.Fatalf("rtc = %v, want %v", m.rtcData, tc.want)
+			}
+		})
+	}
+}
+
+func TestMBC3AdvanceRtc(t *testing.T) {
+	cases := []struct {
+		name    string
+		rtc     []uint8
+		seconds uint64
+	}{
+		{name: "seconds", rtc: []uint8{30, 0, 0, 0, 0}, seconds: 45},
+		{name: "hours and days", rtc: []uint8{12, 34, 5, 6, 0}, seconds: 25*3600 + 7},
+		{name: "into the upper day bit", rtc: []uint8{0, 0, 0, 0xFE, 0}, seconds: 3 * 86400},
+		{name: "past the day carry", rtc: []uint8{1, 2, 3, 0x10, RtcDayHigh}, seconds: 300 * 86400},
+		{name: "out of range registers", rtc: []uint8{62, 61, 25, 0, 0}, seconds: 8 * 3600},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ticked, _ := newTestMBC3(t, CartTypeMbc3TimerRamBattery)
+			copy(ticked.rtcData, tc.rtc)
+			for range tc.seconds {
+				ticked.tickRtc()
+			}
+
+			advanced, _ := newTestMBC3(t, CartTypeMbc3TimerRamBattery)
+			copy(advanced.rtcData, tc.rtc)
+			advanced.advanceRtc(tc.seconds)
+
+			if !slices.Equal(advanced.rtcData, ticked.rtcData) {
+				t.Fatalf("advanceRtc = %v, tickRtc = %v", advanced.rtcData, ticked.rtcData)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package cart
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	. "github.com/indeedhat/gb-emulator/internal/emu/types"
+)
+
+type MBC5 struct {
+	path string
+
+	romBanks uint16
+	romData  []byte
+
+	ramBanks   uint16
+	ramData    []byte
+	ramEnabled bool
+
+	// registers
+	romBank uint16
+	ramBank uint8
+
+	hasBattery    bool
+	hasRamChanges bool
+
+	hasRumble bool
+	rumbling  bool
+	rumbleCh  chan bool
+}
+
+func NewMBC5(path string, data []byte, header *CartHeader) (*MBC5, error) {
+	m := &MBC5{
+		path:     path,
+		romBanks: uint16(len(data) / 0x4000),
+		romData:  data,
+		ramBanks: header.RamBanks(),
+		ramData:  make([]byte, 0x2000*uint32(header.RamBanks())),
+		romBank:  1,
+		hasBattery: CartTypeMbc5RamBattery == header.CartType ||
+			CartTypeMbc5RumbleRamBattery == header.CartType,
+		hasRumble: CartTypeMbc5Rumble == header.CartType ||
+			CartTypeMbc5RumbleRam == header.CartType ||
+			CartTypeMbc5RumbleRamBattery == header.CartType,
+		rumbleCh: make(chan bool, 8),
+	}
+
+	if err := m.Load(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *MBC5) SaveState() []byte {
+	var buf bytes.Buffer
+
+	binary.Write(&buf, binary.BigEndian, int64(len(m.path)))
+	buf.WriteString(m.path)
+
+	binary.Write(&buf, binary.BigEndian, m.romBanks)
+
+	binary.Write(&buf, binary.BigEndian, m.ramBanks)
+	buf.Write(m.ramData)
+	binary.Write(&buf, binary.BigEndian, m.ramEnabled)
+
+	binary.Write(&buf, binary.BigEndian, m.romBank)
+	binary.Write(&buf, binary.BigEndian, m.ramBank)
+	binary.Write(&buf, binary.BigEndian, m.hasBattery)
+	binary.Write(&buf, binary.BigEndian, m.hasRumble)
+	binary.Write(&buf, binary.BigEndian, m.rumbling)
+
+	return buf.Bytes()
+}
+
+func (m *MBC5) LoadState(data []byte) {
+	r := bytes.NewReader(data)
+
+	var strlen int64
+	binary.Read(r, binary.BigEndian, &strlen)
+	buf := make([]byte, strlen)
+	r.Read(buf)
+	m.path = string(buf)
+
+	binary.Read(r, binary.BigEndian, &m.romBanks)
+
+	binary.Read(r, binary.BigEndian, &m.ramBanks)
+	r.Read(m.ramData)
+	binary.Read(r, binary.BigEndian, &m.ramEnabled)
+
+	binary.Read(r, binary.BigEndian, &m.romBank)
+	binary.Read(r, binary.BigEndian, &m.ramBank)
+	binary.Read(r, binary.BigEndian, &m.hasBattery)
+	binary.Read(r, binary.BigEndian, &m.hasRumble)
+
+	var rumbling bool
+	binary.Read(r, binary.BigEndian, &rumbling)
+	m.setRumble(rumbling)
+}
+
+func (m *MBC5) Read(address uint16) byte {
+	var offset uint32
+	switch true {
+	case address < 0x4000:
+		return m.romData[address]
+
+	case address < 0x8000:
+		offset = uint32(m.romBank%m.romBanks) * 0x4000
+		return m.romData[offset+uint32(address-0x4000)]
+
+	case address >= 0xA000 && address < 0xC000:
+		if !m.ramEnabled || m.ramBanks == 0 {
+			return 0xFF
+		}
+
+		offset = uint32(uint16(m.ramBank)%m.ramBanks) * 0x2000
+		return m.ramData[offset+uint32(address-0xA000)]
+	}
+
+	panic("bad cart read")
+}
+
+func (m *MBC5) Write(address uint16, value byte) {
+	switch true {
+	case address < 0x2000:
+		m.ramEnabled = value&0x0F == 0x0A
+
+	case address < 0x3000:
+		m.romBank = m.romBank&0x100 | uint16(value)
+
+	case address < 0x4000:
+		m.romBank = m.romBank&0xFF | uint16(value&0x1)<<8
+
+	case address < 0x6000:
+		// NB: on rumble carts bit 3 drives the motor rather than selecting a ram bank
+		if m.hasRumble {
+			m.setRumble(value&0x08 == 0x08)
+			m.ramBank = value & 0x07
+		} else {
+			m.ramBank = value & 0x0F
+		}
+
+	case address >= 0xA000 && address < 0xC000:
+		if !m.ramEnabled || m.ramBanks == 0 {
+			return
+		}
+
+		m.hasRamChanges = true
+
+		offset := uint32(uint16(m.ramBank)%m.ramBanks) * 0x2000
+		m.ramData[offset+uint32(address-0xA000)] = value
+	}
+}
+
+// RumbleCh implements Rumbler.
+func (m *MBC5) RumbleCh() <-chan bool {
+	return m.rumbleCh
+}
+
+func (m *MBC5) setRumble(on bool) {
+	if on == m.rumbling {
+		return
+	}
+
+	m.rumbling = on
+
+	select {
+	case m.rumbleCh <- on:
+	default:
+	}
+}
+
+// Load implements MBC.
+func (m *MBC5) Load() error {
+	if !m.hasBattery {
+		return nil
+	}
+
+	data, legacy, err := readSave(m.path)
+	if err != nil || data == nil {
+		return err
+	}
+
+	copy(m.ramData, data)
+
+	if legacy {
+		m.hasRamChanges = true
+		return migrateSave(m.path, m.Save)
+	}
+
+	return nil
+}
+
+// Save implements MBC.
+func (m *MBC5) Save() error {
+	if !m.hasBattery || !m.hasRamChanges {
+		return nil
+	}
+
+	m.hasRamChanges = false
+	return writeSave(m.path, m.ramData)
+}
+
+var _ MBC = (*MBC5)(nil)
+var _ Rumbler = (*MBC5)(nil)
@@ -0,0 +1,248 @@
+package cart
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+
+	. "github.com/indeedhat/gb-emulator/internal/emu/types"
+)
+
+const (
+	// accelerometer reading when the console is held flat
+	mbc7TiltCenter = 0x81D0
+	// change in accelerometer reading per g of tilt
+	mbc7TiltScale = 0x70
+)
+
+// MBC7 is the accelerometer cart controller used by Kirby Tilt 'n' Tumble and Command Master
+//
+// it has no ram, instead saves go to a 93LC56 serial eeprom. both the eeprom and the
+// accelerometer are accessed through registers in 0xA000 - 0xAFFF selected by address bits 4-7
+type MBC7 struct {
+	path string
+
+	romBanks uint16
+	romData  []byte
+
+	eeprom *eeprom93lc56
+
+	// registers
+	romBank     uint8
+	ramEnabled1 bool
+	ramEnabled2 bool
+
+	// accelerometer
+	// tiltMu guards tilt, it is set by the input goroutine and latched on the emulation thread
+	tiltMu     sync.Mutex
+	tilt       TiltEvent
+	tiltX      uint16
+	tiltY      uint16
+	tiltErased bool
+}
+
+func NewMBC7(path string, data []byte, header *CartHeader) (*MBC7, error) {
+	m := &MBC7{
+		path:     path,
+		romBanks: header.RomBanks(),
+		romData:  data,
+		eeprom:   newEeprom93lc56(),
+		romBank:  1,
+		tiltX:    0x8000,
+		tiltY:    0x8000,
+	}
+
+	if err := m.Load(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *MBC7) SaveState() []byte {
+	var buf bytes.Buffer
+
+	binary.Write(&buf, binary.BigEndian, int64(len(m.path)))
+	buf.WriteString(m.path)
+
+	binary.Write(&buf, binary.BigEndian, m.romBanks)
+
+	binary.Write(&buf, binary.BigEndian, m.romBank)
+	binary.Write(&buf, binary.BigEndian, m.ramEnabled1)
+	binary.Write(&buf, binary.BigEndian, m.ramEnabled2)
+
+	binary.Write(&buf, binary.BigEndian, m.tiltX)
+	binary.Write(&buf, binary.BigEndian, m.tiltY)
+	binary.Write(&buf, binary.BigEndian, m.tiltErased)
+
+	buf.Write(m.eeprom.data)
+	binary.Write(&buf, binary.BigEndian, m.eeprom.cs)
+	binary.Write(&buf, binary.BigEndian, m.eeprom.clk)
+	binary.Write(&buf, binary.BigEndian, m.eeprom.di)
+	binary.Write(&buf, binary.BigEndian, m.eeprom.do)
+	binary.Write(&buf, binary.BigEndian, m.eeprom.writeEnabled)
+	binary.Write(&buf, binary.BigEndian, m.eeprom.writeAll)
+	binary.Write(&buf, binary.BigEndian, m.eeprom.state)
+	binary.Write(&buf, binary.BigEndian, m.eeprom.shift)
+	binary.Write(&buf, binary.BigEndian, m.eeprom.bits)
+	binary.Write(&buf, binary.BigEndian, m.eeprom.addr)
+
+	return buf.Bytes()
+}
+
+func (m *MBC7) LoadState(data []byte) {
+	r := bytes.NewReader(data)
+
+	var strlen int64
+	binary.Read(r, binary.BigEndian, &strlen)
+	buf := make([]byte, strlen)
+	r.Read(buf)
+	m.path = string(buf)
+
+	binary.Read(r, binary.BigEndian, &m.romBanks)
+
+	binary.Read(r, binary.BigEndian, &m.romBank)
+	binary.Read(r, binary.BigEndian, &m.ramEnabled1)
+	binary.Read(r, binary.BigEndian, &m.ramEnabled2)
+
+	binary.Read(r, binary.BigEndian, &m.tiltX)
+	binary.Read(r, binary.BigEndian, &m.tiltY)
+	binary.Read(r, binary.BigEndian, &m.tiltErased)
+
+	r.Read(m.eeprom.data)
+	binary.Read(r, binary.BigEndian, &m.eeprom.cs)
+	binary.Read(r, binary.BigEndian, &m.eeprom.clk)
+	binary.Read(r, binary.BigEndian, &m.eeprom.di)
+	binary.Read(r, binary.BigEndian, &m.eeprom.do)
+	binary.Read(r, binary.BigEndian, &m.eeprom.writeEnabled)
+	binary.Read(r, binary.BigEndian, &m.eeprom.writeAll)
+	binary.Read(r, binary.BigEndian, &m.eeprom.state)
+	binary.Read(r, binary.BigEndian, &m.eeprom.shift)
+	binary.Read(r, binary.BigEndian, &m.eeprom.bits)
+	binary.Read(r, binary.BigEndian, &m.eeprom.addr)
+}
+
+func (m *MBC7) Read(address uint16) byte {
+	switch true {
+	case address < 0x4000:
+		return m.romData[address]
+
+	case address < 0x8000:
+		offset := uint32(uint16(m.romBank)%m.romBanks) * 0x4000
+		return m.romData[offset+uint32(address-0x4000)]
+
+	case address >= 0xA000 && address < 0xB000:
+		if !m.ramEnabled1 || !m.ramEnabled2 {
+			return 0xFF
+		}
+
+		switch address >> 4 & 0x0F {
+		case 0x2:
+			return uint8(m.tiltX)
+		case 0x3:
+			return uint8(m.tiltX >> 8)
+		case 0x4:
+			return uint8(m.tiltY)
+		case 0x5:
+			return uint8(m.tiltY >> 8)
+		case 0x6:
+			// NB: there is no z axis, this always reads 0
+			return 0x00
+		case 0x8:
+			return m.eeprom.Read()
+		}
+
+		return 0xFF
+
+	case address >= 0xB000 && address < 0xC000:
+		return 0xFF
+	}
+
+	panic("bad cart read")
+}
+
+func (m *MBC7) Write(address uint16, value byte) {
+	switch true {
+	case address < 0x2000:
+		m.ramEnabled1 = value&0x0F == 0x0A
+
+	case address < 0x4000:
+		m.romBank = value & 0x7F
+
+	case address < 0x6000:
+		m.ramEnabled2 = value == 0x40
+
+	case address >= 0xA000 && address < 0xB000:
+		if !m.ramEnabled1 || !m.ramEnabled2 {
+			return
+		}
+
+		switch address >> 4 & 0x0F {
+		case 0x0:
+			if value == 0x55 {
+				m.tiltX = 0x8000
+				m.tiltY = 0x8000
+				m.tiltErased = true
+			}
+		case 0x1:
+			// NB: the accelerometer only latches a new value after being erased
+			if value == 0xAA && m.tiltErased {
+				m.latchTilt()
+				m.tiltErased = false
+			}
+		case 0x8:
+			m.eeprom.Write(value)
+		}
+	}
+}
+
+// SetTilt implements Accelerometer.
+func (m *MBC7) SetTilt(event TiltEvent) {
+	m.tiltMu.Lock()
+	defer m.tiltMu.Unlock()
+
+	m.tilt = event
+}
+
+func (m *MBC7) latchTilt() {
+	m.tiltMu.Lock()
+	defer m.tiltMu.Unlock()
+
+	m.tiltX = tiltReading(m.tilt.X)
+	m.tiltY = tiltReading(m.tilt.Y)
+}
+
+func tiltReading(g float32) uint16 {
+	g = max(-1, min(1, g))
+	return uint16(int32(mbc7TiltCenter) + int32(g*mbc7TiltScale))
+}
+
+// Load implements MBC.
+func (m *MBC7) Load() error {
+	data, legacy, err := readSave(m.path)
+	if err != nil || data == nil {
+		return err
+	}
+
+	copy(m.eeprom.data, data)
+
+	if legacy {
+		m.eeprom.changed = true
+		return migrateSave(m.path, m.Save)
+	}
+
+	return nil
+}
+
+// Save implements MBC.
+func (m *MBC7) Save() error {
+	if !m.eeprom.changed {
+		return nil
+	}
+
+	m.eeprom.changed = false
+	return writeSave(m.path, m.eeprom.data)
+}
+
+var _ MBC = (*MBC7)(nil)
+var _ Accelerometer = (*MBC7)(nil)
@@ -0,0 +1,233 @@
+package cart
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	. "github.com/indeedhat/gb-emulator/internal/emu/types"
+)
+
+// MMM01 is a multicart controller
+//
+// on power up it maps the menu from the last 32 KiB of the rom into 0x0000 - 0x7FFF, the menu
+// selects the base bank of the chosen game and then locks the mapper. once locked it behaves
+// like a simple mbc with all rom banks offset by the selected base bank until the next reset
+//
+// the menu also sets masks before locking, bank bits covered by a mask keep the value latched by
+// the menu so each game only sees its own slice of the rom and ram
+type MMM01 struct {
+	path string
+
+	romBanks uint16
+	romData  []byte
+
+	ramBanks   uint16
+	ramData    []byte
+	ramEnabled bool
+
+	// registers
+	locked   bool
+	baseBank uint16
+	romBank  uint8
+	ramBank  uint8
+	mode     uint8
+	// romMask covers rom bank bits 1-4, ramMask covers ram bank bits 0-1
+	romMask uint8
+	ramMask uint8
+
+	hasBattery    bool
+	hasRamChanges bool
+}
+
+func NewMMM01(path string, data []byte, header *CartHeader) (*MMM01, error) {
+	m := &MMM01{
+		path:       path,
+		romBanks:   uint16(len(data) / 0x4000),
+		romData:    data,
+		ramBanks:   header.RamBanks(),
+		ramData:    make([]byte, 0x2000*uint32(header.RamBanks())),
+		romBank:    1,
+		hasBattery: CartTypeMMM01RamBattery == header.CartType,
+	}
+
+	if err := m.Load(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *MMM01) SaveState() []byte {
+	var buf bytes.Buffer
+
+	binary.Write(&buf, binary.BigEndian, int64(len(m.path)))
+	buf.WriteString(m.path)
+
+	binary.Write(&buf, binary.BigEndian, m.romBanks)
+
+	binary.Write(&buf, binary.BigEndian, m.ramBanks)
+	buf.Write(m.ramData)
+	binary.Write(&buf, binary.BigEndian, m.ramEnabled)
+
+	binary.Write(&buf, binary.BigEndian, m.locked)
+	binary.Write(&buf, binary.BigEndian, m.baseBank)
+	binary.Write(&buf, binary.BigEndian, m.romBank)
+	binary.Write(&buf, binary.BigEndian, m.ramBank)
+	binary.Write(&buf, binary.BigEndian, m.mode)
+	binary.Write(&buf, binary.BigEndian, m.romMask)
+	binary.Write(&buf, binary.BigEndian, m.ramMask)
+	binary.Write(&buf, binary.BigEndian, m.hasBattery)
+
+	return buf.Bytes()
+}
+
+func (m *MMM01) LoadState(data []byte) {
+	r := bytes.NewReader(data)
+
+	var strlen int64
+	binary.Read(r, binary.BigEndian, &strlen)
+	buf := make([]byte, strlen)
+	r.Read(buf)
+	m.path = string(buf)
+
+	binary.Read(r, binary.BigEndian, &m.romBanks)
+
+	binary.Read(r, binary.BigEndian, &m.ramBanks)
+	r.Read(m.ramData)
+	binary.Read(r, binary.BigEndian, &m.ramEnabled)
+
+	binary.Read(r, binary.BigEndian, &m.locked)
+	binary.Read(r, binary.BigEndian, &m.baseBank)
+	binary.Read(r, binary.BigEndian, &m.romBank)
+	binary.Read(r, binary.BigEndian, &m.ramBank)
+	binary.Read(r, binary.BigEndian, &m.mode)
+	binary.Read(r, binary.BigEndian, &m.romMask)
+	binary.Read(r, binary.BigEndian, &m.ramMask)
+	binary.Read(r, binary.BigEndian, &m.hasBattery)
+}
+
+func (m *MMM01) Read(address uint16) byte {
+	switch true {
+	case address < 0x8000 && !m.locked:
+		return m.romData[len(m.romData)-0x8000+int(address)]
+
+	case address < 0x4000:
+		return m.romData[m.romOffset(0)+uint32(address)]
+
+	case address < 0x8000:
+		return m.romData[m.romOffset(m.romBank)+uint32(address-0x4000)]
+
+	case address >= 0xA000 && address < 0xC000:
+		if !m.ramEnabled || m.ramBanks == 0 {
+			return 0xFF
+		}
+
+		return m.ramData[m.ramOffset()+uint32(address-0xA000)]
+	}
+
+	panic("bad cart read")
+}
+
+func (m *MMM01) Write(address uint16, value byte) {
+	switch true {
+	case address < 0x2000:
+		m.ramEnabled = value&0x0F == 0x0A
+
+		// NB: once locked the mapping can only be undone by a reset
+		if !m.locked {
+			m.ramMask = value >> 4 & 0x03
+			m.locked = value&0x40 == 0x40
+		}
+
+	case address < 0x4000:
+		if !m.locked {
+			m.baseBank = m.baseBank&^0x7F | uint16(value&0x7F)
+			return
+		}
+
+		m.romBank = value & 0x1F
+		if m.romBank == 0 {
+			m.romBank = 1
+		}
+
+	case address < 0x6000:
+		if !m.locked {
+			m.baseBank = m.baseBank&^0x180 | uint16(value&0x30)<<3
+			m.ramBank = value & 0x0F
+			return
+		}
+
+		selectable := 0x03 &^ m.ramMask
+		m.ramBank = m.ramBank&^selectable | value&selectable
+
+	case address < 0x8000:
+		if !m.locked {
+			m.romMask = value >> 2 & 0x0F
+		}
+
+		m.mode = value & 0x01
+
+	case address >= 0xA000 && address < 0xC000:
+		if !m.ramEnabled || m.ramBanks == 0 {
+			return
+		}
+
+		m.hasRamChanges = true
+
+		m.ramData[m.ramOffset()+uint32(address-0xA000)] = value
+	}
+}
+
+// romOffset is the start of the given bank within the game selected by the menu
+//
+// the bits of the bank that are not covered by the rom mask are chosen by the game, the rest come
+// from the base bank latched by the menu
+func (m *MMM01) romOffset(bank uint8) uint32 {
+	selectable := uint16(0x1F &^ (m.romMask << 1))
+	full := m.baseBank&^selectable | uint16(bank)&selectable
+
+	return uint32(full%m.romBanks) * 0x4000
+}
+
+// ramOffset is the start of the selected ram bank, the unmasked bits are only used in mode 1
+func (m *MMM01) ramOffset() uint32 {
+	bank := m.ramBank
+	if m.mode == 0 {
+		bank &^= 0x03 &^ m.ramMask
+	}
+
+	return uint32(uint16(bank)%m.ramBanks) * 0x2000
+}
+
+// Load implements MBC.
+func (m *MMM01) Load() error {
+	if !m.hasBattery {
+		return nil
+	}
+
+	data, legacy, err := readSave(m.path)
+	if err != nil || data == nil {
+		return err
+	}
+
+	copy(m.ramData, data)
+
+	if legacy {
+		m.hasRamChanges = true
+		return migrateSave(m.path, m.Save)
+	}
+
+	return nil
+}
+
+// Save implements MBC.
+func (m *MMM01) Save() error {
+	if !m.hasBattery || !m.hasRamChanges {
+		return nil
+	}
+
+	m.hasRamChanges = false
+	return writeSave(m.path, m.ramData)
+}
+
+var _ MBC = (*MMM01)(nil)
@@ -0,0 +1,46 @@
+package cart
+
+import "testing"
+
+func TestMMM01Banking(t *testing.T) {
+	// NB: the menu selects an 8 bank game starting at bank 8, masking rom bank bits 3-4 and ram
+	//     bank bit 1 before locking the mapper
+	menu := [][2]uint16{
+		{0x2000, 0x08},
+		{0x4000, 0x02},
+		{0x6000, 0b1100 << 2},
+		{0x0000, 0x0A | 0b10<<4 | 0x40},
+	}
+
+	cases := []struct {
+		name    string
+		writes  [][2]uint16
+		address uint16
+		want    uint8
+	}{
+		{name: "bank 0 is the first bank of the game", address: 0x0000, want: 0x08},
+		{name: "switchable bank within the game", writes: [][2]uint16{{0x2000, 0x03}}, address: 0x4000, want: 0x0B},
+		{name: "masked bits can't reach the next game", writes: [][2]uint16{{0x2000, 0x1A}}, address: 0x4000, want: 0x0A},
+		{name: "ram bank keeps the masked bit in mode 0", writes: [][2]uint16{{0xA000, 0x42}, {0x6000, 0x01}, {0x4000, 0x02}}, address: 0xA000, want: 0x42},
+		{name: "ram bank selects the unmasked bit in mode 1", writes: [][2]uint16{{0xA000, 0x42}, {0x6000, 0x01}, {0x4000, 0x01}}, address: 0xA000, want: 0x00},
+		{name: "ram bank can't clear the masked bit", writes: [][2]uint16{{0x6000, 0x01}, {0xA000, 0x42}, {0x4000, 0x00}, {0x6000, 0x00}}, address: 0xA000, want: 0x42},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			header := &CartHeader{CartType: CartTypeMMM01Ram, RamSize: 0x03}
+			m, err := NewMMM01("", newTestRom(64), header)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, w := range append(menu, tc.writes...) {
+				m.Write(w[0], uint8(w[1]))
+			}
+
+			if got := m.Read(tc.address); got != tc.want {
+				t.Fatalf("read 0x%04X = 0x%02X, want 0x%02X", tc.address, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package cart
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+
+	. "github.com/indeedhat/gb-emulator/internal/emu/types"
+)
+
+const (
+	camSensorWidth  = 128
+	camSensorHeight = 112
+
+	// captured images are written to the start of ram bank 0 as 2bpp tiles
+	camImageOffset = 0x100
+
+	camRegisterCount = 0x36
+	camRegTrigger    = 0x00
+	camRegGain       = 0x01
+	camRegExposureHi = 0x02
+	camRegExposureLo = 0x03
+	camRegEdge       = 0x04
+	camRegMatrix     = 0x06
+)
+
+// edge enhancement ratios selected by bits 4-6 of camRegEdge
+var camEdgeRatios = [8]float32{0.5, 0.75, 1, 1.25, 2, 3, 4, 5}
+
+// PocketCamera is the mapper used by the game boy camera
+//
+// as well as 128 KiB of banked ram it exposes the registers of the M64282FP image sensor
+// when bit 4 of the ram bank register is set. images are taken from the attached CameraSource
+// and processed with the exposure, edge enhancement and dithering matrix set by the rom
+type PocketCamera struct {
+	path string
+
+	romBanks uint16
+	romData  []byte
+
+	ramData    []byte
+	ramEnabled bool
+
+	// registers
+	romBank         uint8
+	ramBank         uint8
+	registersMapped bool
+	registers       []byte
+
+	// remaining M-cycles before the current capture completes
+	captureCycles uint32
+
+	source CameraSource
+
+	hasRamChanges bool
+}
+
+func NewPocketCamera(path string, data []byte, header *CartHeader) (*PocketCamera, error) {
+	m := &PocketCamera{
+		path:      path,
+		romBanks:  header.RomBanks(),
+		romData:   data,
+		ramData:   make([]byte, 0x20000),
+		romBank:   1,
+		registers: make([]byte, camRegisterCount),
+	}
+
+	if err := m.Load(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *PocketCamera) SaveState() []byte {
+	var buf bytes.Buffer
+
+	binary.Write(&buf, binary.BigEndian, int64(len(m.path)))
+	buf.WriteString(m.path)
+
+	binary.Write(&buf, binary.BigEndian, m.romBanks)
+
+	buf.Write(m.ramData)
+	binary.Write(&buf, binary.BigEndian, m.ramEnabled)
+
+	binary.Write(&buf, binary.BigEndian, m.romBank)
+	binary.Write(&buf, binary.BigEndian, m.ramBank)
+	binary.Write(&buf, binary.BigEndian, m.registersMapped)
+	buf.Write(m.registers)
+	binary.Write(&buf, binary.BigEndian, m.captureCycles)
+
+	return buf.Bytes()
+}
+
+func (m *PocketCamera) LoadState(data []byte) {
+	r := bytes.NewReader(data)
+
+	var strlen int64
+	binary.Read(r, binary.BigEndian, &strlen)
+	buf := make([]byte, strlen)
+	r.Read(buf)
+	m.path = string(buf)
+
+	binary.Read(r, binary.BigEndian, &m.romBanks)
+
+	r.Read(m.ramData)
+	binary.Read(r, binary.BigEndian, &m.ramEnabled)
+
+	binary.Read(r, binary.BigEndian, &m.romBank)
+	binary.Read(r, binary.BigEndian, &m.ramBank)
+	binary.Read(r, binary.BigEndian, &m.registersMapped)
+	r.Read(m.registers)
+	binary.Read(r, binary.BigEndian, &m.captureCycles)
+}
+
+func (m *PocketCamera) Read(address uint16) byte {
+	switch true {
+	case address < 0x4000:
+		return m.romData[address]
+
+	case address < 0x8000:
+		offset := uint32(uint16(m.romBank)%m.romBanks) * 0x4000
+		return m.romData[offset+uint32(address-0x4000)]
+
+	case address >= 0xA000 && address < 0xC000:
+		if m.registersMapped {
+			// NB: only the trigger register can be read back, the rest are write only
+			if address&0x7F == camRegTrigger {
+				return m.registers[camRegTrigger]
+			}
+			return 0x00
+		}
+
+		// NB: ram is disconnected from the bus while the sensor is writing to it
+		if m.captureCycles > 0 {
+			return 0x00
+		}
+
+		offset := uint32(m.ramBank) * 0x2000
+		return m.ramData[offset+uint32(address-0xA000)]
+	}
+
+	panic("bad cart read")
+}
+
+func (m *PocketCamera) Write(address uint16, value byte) {
+	switch true {
+	case address < 0x2000:
+		m.ramEnabled = value&0x0F == 0x0A
+
+	case address < 0x4000:
+		m.romBank = value & 0x3F
+
+	case address < 0x6000:
+		m.registersMapped = value&0x10 == 0x10
+		if !m.registersMapped {
+			m.ramBank = value & 0x0F
+		}
+
+	case address >= 0xA000 && address < 0xC000:
+		if m.registersMapped {
+			m.writeRegister(uint8(address&0x7F), value)
+			return
+		}
+
+		if !m.ramEnabled || m.captureCycles > 0 {
+			return
+		}
+
+		m.hasRamChanges = true
+
+		offset := uint32(m.ramBank) * 0x2000
+		m.ramData[offset+uint32(address-0xA000)] = value
+	}
+}
+
+func (m *PocketCamera) writeRegister(reg, value uint8) {
+	if reg >= camRegisterCount {
+		return
+	}
+
+	if reg != camRegTrigger {
+		m.registers[reg] = value
+		return
+	}
+
+	m.registers[camRegTrigger] = m.registers[camRegTrigger]&0x01 | value&0x06
+
+	if value&0x01 == 0x01 && m.captureCycles == 0 {
+		m.registers[camRegTrigger] |= 0x01
+		m.captureCycles = m.captureTime()
+	}
+}
+
+// captureTime returns the number of M-cycles a capture takes with the current settings
+func (m *PocketCamera) captureTime() uint32 {
+	exposure := uint32(m.registers[camRegExposureHi])<<8 | uint32(m.registers[camRegExposureLo])
+
+	cycles := 32446 + 16*exposure
+	if m.registers[camRegGain]&0x80 == 0 {
+		cycles += 512
+	}
+
+	return cycles
+}
+
+// Tick implements Ticker.
+func (m *PocketCamera) Tick() {
+	if m.captureCycles == 0 {
+		return
+	}
+
+	m.captureCycles--
+	if m.captureCycles > 0 {
+		return
+	}
+
+	m.capture()
+	m.registers[camRegTrigger] &^= 0x01
+}
+
+// SetCameraSource implements CameraSensor.
+func (m *PocketCamera) SetCameraSource(source CameraSource) {
+	m.source = source
+}
+
+func (m *PocketCamera) capture() {
+	pixels := m.sense()
+
+	var (
+		invert    = m.registers[camRegEdge]&0x08 == 0x08
+		enhance   = m.registers[camRegGain]&0x60 == 0x60
+		edgeRatio = camEdgeRatios[m.registers[camRegEdge]>>4&0x07]
+		exposure  = float32(uint16(m.registers[camRegExposureHi])<<8|uint16(m.registers[camRegExposureLo])) / 0x300
+	)
+
+	at := func(x, y int) float32 {
+		x = max(0, min(camSensorWidth-1, x))
+		y = max(0, min(camSensorHeight-1, y))
+
+		value := float32(pixels[y*camSensorWidth+x])
+		if invert {
+			value = 255 - value
+		}
+
+		return value * exposure
+	}
+
+	for y := range camSensorHeight {
+		for x := range camSensorWidth {
+			value := at(x, y)
+
+			if enhance {
+				edges := 4*value - at(x-1, y) - at(x+1, y) - at(x, y-1) - at(x, y+1)
+				value += edgeRatio * edges / 4
+			}
+
+			m.writeCapturePixel(x, y, m.dither(x, y, value))
+		}
+	}
+
+	m.hasRamChanges = true
+}
+
+// dither maps a processed sensor value to a 2 bit colour using the 4x4 threshold matrix
+//
+// each matrix entry holds three ascending thresholds, values under the first are black
+func (m *PocketCamera) dither(x, y int, value float32) uint8 {
+	value = max(0, min(255, value))
+
+	idx := camRegMatrix + ((y&3)*4+(x&3))*3
+	thresholds := m.registers[idx : idx+3]
+
+	switch true {
+	case value < float32(thresholds[0]):
+		return 3
+	case value < float32(thresholds[1]):
+		return 2
+	case value < float32(thresholds[2]):
+		return 1
+	}
+
+	return 0
+}
+
+func (m *PocketCamera) writeCapturePixel(x, y int, color uint8) {
+	tile := (y/8)*(camSensorWidth/8) + x/8
+	offset := camImageOffset + tile*16 + (y%8)*2
+	bit := uint8(7 - x%8)
+
+	m.ramData[offset] = m.ramData[offset]&^(1<<bit) | (color&0x01)<<bit
+	m.ramData[offset+1] = m.ramData[offset+1]&^(1<<bit) | (color>>1)<<bit
+}
+
+// sense returns the grey scale image seen by the sensor
+//
+// the source frame is scaled to cover the sensor, cropping any overflow from the edges
+func (m *PocketCamera) sense() []uint8 {
+	pixels := make([]uint8, camSensorWidth*camSensorHeight)
+
+	var frame image.Image
+	if m.source != nil {
+		frame = m.source.Frame()
+	}
+
+	// NB: without a source the sensor sees a flat mid grey
+	if frame == nil {
+		for i := range pixels {
+			pixels[i] = 0x80
+		}
+		return pixels
+	}
+
+	bounds := frame.Bounds()
+	scale := min(
+		float32(bounds.Dx())/camSensorWidth,
+		float32(bounds.Dy())/camSensorHeight,
+	)
+	offsetX := (float32(bounds.Dx()) - camSensorWidth*scale) / 2
+	offsetY := (float32(bounds.Dy()) - camSensorHeight*scale) / 2
+
+	for y := range camSensorHeight {
+		for x := range camSensorWidth {
+			sx := bounds.Min.X + int(offsetX+float32(x)*scale)
+			sy := bounds.Min.Y + int(offsetY+float32(y)*scale)
+
+			r, g, b, _ := frame.At(sx, sy).RGBA()
+			pixels[y*camSensorWidth+x] = uint8((299*r + 587*g + 114*b) / 1000 >> 8)
+		}
+	}
+
+	return pixels
+}
+
+// Load implements MBC.
+func (m *PocketCamera) Load() error {
+	data, legacy, err := readSave(m.path)
+	if err != nil || data == nil {
+		return err
+	}
+
+	copy(m.ramData, data)
+
+	if legacy {
+		m.hasRamChanges = true
+		return migrateSave(m.path, m.Save)
+	}
+
+	return nil
+}
+
+// Save implements MBC.
+func (m *PocketCamera) Save() error {
+	if !m.hasRamChanges {
+		return nil
+	}
+
+	m.hasRamChanges = false
+	return writeSave(m.path, m.ramData)
+}
+
+var _ MBC = (*PocketCamera)(nil)
+var _ Ticker = (*PocketCamera)(nil)
+var _ CameraSensor = (*PocketCamera)(nil)
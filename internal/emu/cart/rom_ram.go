@@ -0,0 +1,95 @@
+package cart
+
+import . "github.com/indeedhat/gb-emulator/internal/emu/types"
+
+// RomRam is a cart with no mbc controller but with a single unbanked 8 KiB ram chip
+// mapped into 0xA000 - 0xBFFF
+type RomRam struct {
+	path string
+
+	romData []byte
+	ramData []byte
+
+	hasBattery    bool
+	hasRamChanges bool
+}
+
+func NewRomRam(path string, data []byte, header *CartHeader) (*RomRam, error) {
+	m := &RomRam{
+		path:       path,
+		romData:    data,
+		ramData:    make([]byte, 0x2000),
+		hasBattery: CartTypeRomRamBattery == header.CartType,
+	}
+
+	if err := m.Load(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *RomRam) SaveState() []byte {
+	return append([]byte(nil), m.ramData...)
+}
+
+func (m *RomRam) LoadState(data []byte) {
+	copy(m.ramData, data)
+}
+
+func (m *RomRam) Read(address uint16) byte {
+	switch true {
+	case address < 0x8000:
+		if int(address) >= len(m.romData) {
+			return 0xFF
+		}
+		return m.romData[address]
+
+	case address >= 0xA000 && address < 0xC000:
+		return m.ramData[address-0xA000]
+	}
+
+	panic("bad cart read")
+}
+
+func (m *RomRam) Write(address uint16, value byte) {
+	if address < 0xA000 || address >= 0xC000 {
+		return
+	}
+
+	m.hasRamChanges = true
+	m.ramData[address-0xA000] = value
+}
+
+// Load implements MBC.
+func (m *RomRam) Load() error {
+	if !m.hasBattery {
+		return nil
+	}
+
+	data, legacy, err := readSave(m.path)
+	if err != nil || data == nil {
+		return err
+	}
+
+	copy(m.ramData, data)
+
+	if legacy {
+		m.hasRamChanges = true
+		return migrateSave(m.path, m.Save)
+	}
+
+	return nil
+}
+
+// Save implements MBC.
+func (m *RomRam) Save() error {
+	if !m.hasBattery || !m.hasRamChanges {
+		return nil
+	}
+
+	m.hasRamChanges = false
+	return writeSave(m.path, m.ramData)
+}
+
+var _ MBC = (*RomRam)(nil)
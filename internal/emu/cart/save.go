@@ -0,0 +1,54 @@
+package cart
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// saves written by older versions of the emulator
+const legacySaveExt = ".gbsav"
+
+// savePath returns the path of the battery save for a rom
+//
+// the rom extension is replaced with .sav to match the naming used by other emulators and flash carts
+func savePath(romPath string) string {
+	return strings.TrimSuffix(romPath, filepath.Ext(romPath)) + ".sav"
+}
+
+// readSave reads the battery save for a rom, a nil slice is returned if there is no save
+//
+// if there is no .sav file it falls back to the legacy .gbsav file, legacy will be set so the
+// mbc can convert it before writing it back out with migrateSave
+func readSave(romPath string) (data []byte, legacy bool, err error) {
+	data, err = os.ReadFile(savePath(romPath))
+	if err == nil {
+		return data, false, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, false, err
+	}
+
+	data, err = os.ReadFile(romPath + legacySaveExt)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	return data, true, nil
+}
+
+func writeSave(romPath string, data []byte) error {
+	return os.WriteFile(savePath(romPath), data, 0644)
+}
+
+// migrateSave writes a save loaded from a legacy file back out as a .sav and removes the legacy file
+func migrateSave(romPath string, save func() error) error {
+	if err := save(); err != nil {
+		return err
+	}
+
+	return os.Remove(romPath + legacySaveExt)
+}
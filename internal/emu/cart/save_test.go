@@ -0,0 +1,187 @@
+package cart
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+var (
+	testRtcLive    = []uint8{1, 2, 3, 4, RtcHalt}
+	testRtcLatched = []uint8{5, 6, 7, 8, RtcHalt | RtcDayHigh}
+)
+
+// rtcFooter builds a save footer, size is either the 48 byte footer or the 44 byte variant with a
+// 32 bit timestamp
+func rtcFooter(live, latched []uint8, saved int64, size int) []byte {
+	var footer []byte
+	for _, value := range live {
+		footer = binary.LittleEndian.AppendUint32(footer, uint32(value))
+	}
+	for _, value := range latched {
+		footer = binary.LittleEndian.AppendUint32(footer, uint32(value))
+	}
+
+	if size == rtcFooterSize {
+		return binary.LittleEndian.AppendUint64(footer, uint64(saved))
+	}
+
+	return binary.LittleEndian.AppendUint32(footer, uint32(saved))
+}
+
+func TestMBC3SaveFooter(t *testing.T) {
+	m, romPath := newTestMBC3(t, CartTypeMbc3TimerRamBattery)
+	m.ramData[0] = 0x42
+	copy(m.rtcData, testRtcLive)
+	copy(m.rtcLatchedData, testRtcLatched)
+	m.hasRamChanges = true
+
+	before := time.Now().Unix()
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(savePath(romPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data) != 0x2000+rtcFooterSize {
+		t.Fatalf("save is %d bytes, want %d", len(data), 0x2000+rtcFooterSize)
+	}
+	if data[0] != 0x42 {
+		t.Fatalf("ram[0] = 0x%02X, want 0x42", data[0])
+	}
+
+	footer := data[0x2000:]
+	for i := range 5 {
+		if got := binary.LittleEndian.Uint32(footer[i*4:]); got != uint32(testRtcLive[i]) {
+			t.Fatalf("live register %d at offset %d = %d, want %d", i, i*4, got, testRtcLive[i])
+		}
+		if got := binary.LittleEndian.Uint32(footer[20+i*4:]); got != uint32(testRtcLatched[i]) {
+			t.Fatalf("latched register %d at offset %d = %d, want %d", i, 20+i*4, got, testRtcLatched[i])
+		}
+	}
+
+	if saved := int64(binary.LittleEndian.Uint64(footer[40:])); saved < before || saved > time.Now().Unix() {
+		t.Fatalf("timestamp at offset 40 = %d, want the time of the save", saved)
+	}
+}
+
+func TestMBC3LoadSave(t *testing.T) {
+	ram := make([]byte, 0x2000)
+	ram[0] = 0x42
+
+	legacy := slices.Concat(testRtcLive, ram)
+	legacy = binary.BigEndian.AppendUint64(legacy, uint64(time.Now().Unix()))
+
+	running := []uint8{0, 0, 1, 0, 0}
+
+	cases := []struct {
+		name        string
+		cartType    byte
+		legacy      bool
+		data        []byte
+		wantRtc     []uint8
+		wantLatched []uint8
+		// the offline time can be a second out depending on when the test runs
+		skipSeconds bool
+	}{
+		{
+			name:        "48 byte footer",
+			cartType:    CartTypeMbc3TimerRamBattery,
+			data:        slices.Concat(ram, rtcFooter(testRtcLive, testRtcLatched, time.Now().Unix(), rtcFooterSize)),
+			wantRtc:     testRtcLive,
+			wantLatched: testRtcLatched,
+		},
+		{
+			name:        "44 byte footer",
+			cartType:    CartTypeMbc3TimerRamBattery,
+			data:        slices.Concat(ram, rtcFooter(testRtcLive, testRtcLatched, time.Now().Unix(), rtcFooterSize-4)),
+			wantRtc:     testRtcLive,
+			wantLatched: testRtcLatched,
+		},
+		{
+			name:        "offline time is applied to the live registers",
+			cartType:    CartTypeMbc3TimerRamBattery,
+			data:        slices.Concat(ram, rtcFooter(running, running, time.Now().Unix()-3600, rtcFooterSize)),
+			wantRtc:     []uint8{0, 0, 2, 0, 0},
+			wantLatched: running,
+			skipSeconds: true,
+		},
+		{
+			name:        "ram only save without an rtc",
+			cartType:    CartTypeMbc3RamBattery,
+			data:        ram,
+			wantRtc:     make([]uint8, 5),
+			wantLatched: make([]uint8, 5),
+		},
+		{
+			name:        "legacy gbsav",
+			cartType:    CartTypeMbc3TimerRamBattery,
+			legacy:      true,
+			data:        legacy,
+			wantRtc:     testRtcLive,
+			wantLatched: testRtcLive,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			romPath := filepath.Join(t.TempDir(), "test.gb")
+
+			path := savePath(romPath)
+			if tc.legacy {
+				path = romPath + legacySaveExt
+			}
+			if err := os.WriteFile(path, tc.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			header := &CartHeader{CartType: tc.cartType, RamSize: 0x02}
+			m, err := NewMBC3(romPath, newTestRom(4), header)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if m.ramData[0] != 0x42 {
+				t.Fatalf("ram[0] = 0x%02X, want 0x42", m.ramData[0])
+			}
+			rtc := slices.Clone(m.rtcData)
+			if tc.skipSeconds {
+				rtc[RtcSeconds] = tc.wantRtc[RtcSeconds]
+			}
+			if !slices.Equal(rtc, tc.wantRtc) {
+				t.Fatalf("rtc = %v, want %v", m.rtcData, tc.wantRtc)
+			}
+			if !slices.Equal(m.rtcLatchedData, tc.wantLatched) {
+				t.Fatalf("latched rtc = %v, want %v", m.rtcLatchedData, tc.wantLatched)
+			}
+
+			if !tc.legacy {
+				return
+			}
+
+			// NB: legacy saves are converted to a .sav with a footer and removed
+			if _, err := os.Stat(romPath + legacySaveExt); !errors.Is(err, fs.ErrNotExist) {
+				t.Fatalf("legacy save was not removed: %v", err)
+			}
+
+			data, err := os.ReadFile(savePath(romPath))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(data) != 0x2000+rtcFooterSize || data[0] != 0x42 {
+				t.Fatalf("migrated save is %d bytes starting 0x%02X", len(data), data[0])
+			}
+			if got := uint8(binary.LittleEndian.Uint32(data[0x2000:])); got != testRtcLive[RtcSeconds] {
+				t.Fatalf("migrated seconds = %d, want %d", got, testRtcLive[RtcSeconds])
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package config
+
+const (
+	CpuClockSpeed     = 4194304
+	ApuTicksPerSample = 64
+	ApuSampleRate     = CpuClockSpeed / ApuTicksPerSample
+	ApuBufferSize     = 1024
+	ApuFrameSeqTicks  = 8192
+)
@@ -3,14 +3,27 @@ package context
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 
 	. "github.com/indeedhat/gb-emulator/internal/emu/enum"
 	. "github.com/indeedhat/gb-emulator/internal/emu/types"
 )
 
+var ErrStateModeMismatch = errors.New("save state was created in a different hardware mode")
+
 type Context struct {
 	ticks uint64
 
+	CgbMode bool
+
+	// BootRom is mapped over the start of the cart until the boot sequence is complete
+	// when it is nil the emulator skips straight to the post boot state
+	BootRom []byte
+
+	Apu interface {
+		ReadWriter
+		Ticker
+	}
 	Cart interface {
 		ReadWriter
 		SaveLoader
@@ -25,6 +38,9 @@ type Context struct {
 		SetInterruptFlags(value uint8)
 		InterruptRegister() uint8
 		SetInterruptRegister(value uint8)
+		DoubleSpeed() bool
+		Key1() uint8
+		SetKey1(value uint8)
 	}
 	Debug interface {
 		Update()
@@ -37,6 +53,13 @@ type Context struct {
 		Active() bool
 		Start(value uint8)
 	}
+	Hdma interface {
+		ReadWriter
+		Ticker
+
+		Transferring() bool
+		Hblank()
+	}
 	Lcd interface {
 		ReadWriter
 
@@ -58,11 +81,12 @@ type Context struct {
 		BgTileAddress(address uint16) uint16
 		BackgroundPallet() uint8
 		ObjectPallet(i uint8) uint8
+		CgbBackgroundColor(palette, colorIdx uint8) Pixel
+		CgbObjectColor(palette, colorIdx uint8) Pixel
 	}
 	Bus ReadWriter16
 	Pix interface {
 		WindowVisible() bool
-		IncrementWindowX()
 	}
 	Ppu interface {
 		ReadWriter
@@ -72,22 +96,49 @@ type Context struct {
 		ReadWriter
 		Ticker
 	}
-	Io ReadWriter
+	Io     ReadWriter
+	Serial interface {
+		ReadWriter
+		Ticker
+
+		Connect(cable LinkCable)
+	}
+
+	// CartClock is set for carts with hardware that runs off the system clock, it is ticked
+	// once per M-cycle at normal speed
+	CartClock Ticker
 
 	FrameCh  chan []Pixel
+	AudioCh  chan []Sample
 	JoypadCh chan KeyEvent
+	TiltCh   chan TiltEvent
+
+	// AudioLossless makes the apu wait for AudioCh to be drained instead of dropping buffers
+	AudioLossless bool
 }
 
 func NewContext() *Context {
 	return &Context{
 		FrameCh:  make(chan []Pixel, 2),
+		AudioCh:  make(chan []Sample, 4),
 		JoypadCh: make(chan KeyEvent, 2),
+		TiltCh:   make(chan TiltEvent, 2),
 	}
 }
 
-func (c *Context) LoadState(data []byte) {
+// LoadState restores a state created by SaveState
+//
+// states can only be loaded in the hardware mode they were saved in as the cgb registers and
+// banks are not valid in dmg mode (and vice versa)
+func (c *Context) LoadState(data []byte) error {
 	r := bytes.NewReader(data)
 
+	var cgbMode bool
+	binary.Read(r, binary.BigEndian, &cgbMode)
+	if cgbMode != c.CgbMode {
+		return ErrStateModeMismatch
+	}
+
 	var size int64
 	binary.Read(r, binary.BigEndian, &size)
 	tmp := make([]byte, size)
@@ -128,11 +179,30 @@ func (c *Context) LoadState(data []byte) {
 	tmp = make([]byte, size)
 	r.Read(tmp)
 	c.Timer.(Stator).LoadState(tmp)
+
+	binary.Read(r, binary.BigEndian, &size)
+	tmp = make([]byte, size)
+	r.Read(tmp)
+	c.Apu.(Stator).LoadState(tmp)
+
+	binary.Read(r, binary.BigEndian, &size)
+	tmp = make([]byte, size)
+	r.Read(tmp)
+	c.Hdma.(Stator).LoadState(tmp)
+
+	binary.Read(r, binary.BigEndian, &size)
+	tmp = make([]byte, size)
+	r.Read(tmp)
+	c.Serial.(Stator).LoadState(tmp)
+
+	return nil
 }
 
 func (c *Context) SaveState() []byte {
 	var buf bytes.Buffer
 
+	binary.Write(&buf, binary.BigEndian, c.CgbMode)
+
 	tmp := c.Cart.(Stator).SaveState()
 	binary.Write(&buf, binary.BigEndian, int64(len(tmp)))
 	buf.Write(tmp)
@@ -165,6 +235,18 @@ func (c *Context) SaveState() []byte {
 	binary.Write(&buf, binary.BigEndian, int64(len(tmp)))
 	buf.Write(tmp)
 
+	tmp = c.Apu.(Stator).SaveState()
+	binary.Write(&buf, binary.BigEndian, int64(len(tmp)))
+	buf.Write(tmp)
+
+	tmp = c.Hdma.(Stator).SaveState()
+	binary.Write(&buf, binary.BigEndian, int64(len(tmp)))
+	buf.Write(tmp)
+
+	tmp = c.Serial.(Stator).SaveState()
+	binary.Write(&buf, binary.BigEndian, int64(len(tmp)))
+	buf.Write(tmp)
+
 	return buf.Bytes()
 }
 
@@ -173,13 +255,30 @@ func (c *Context) Ticks() uint64 {
 }
 
 func (c *Context) EmuCycle(i uint8) {
+	doubleSpeed := c.Cpu.DoubleSpeed()
+
 	for range i {
-		for range 4 {
+		for t := range 4 {
 			c.ticks++
 			c.Timer.Tick()
+
+			// NB: in double speed mode the ppu and apu keep running at normal speed
+			//     so only get half the ticks per cpu cycle
+			if doubleSpeed && t%2 == 1 {
+				continue
+			}
+
 			c.Ppu.Tick()
+			c.Apu.Tick()
 		}
 
 		c.Dma.Tick()
+		c.Hdma.Tick()
+		c.Serial.Tick()
+
+		// NB: the cart is not affected by double speed mode
+		if c.CartClock != nil && (!doubleSpeed || c.ticks%8 == 0) {
+			c.CartClock.Tick()
+		}
 	}
 }
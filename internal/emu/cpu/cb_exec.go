@@ -1,8 +1,6 @@
 package cpu
 
 func (c *Cpu) execCB(_ CpuInstriction, cbyte uint16) bool {
-	c.ctx.EmuCycle(1)
-
 	if bitOp := uint8(cbyte >> 6 & 0x03); bitOp != 0 {
 		c.execCB_BitOp(bitOp, cbyte)
 		return true
@@ -11,10 +9,6 @@ func (c *Cpu) execCB(_ CpuInstriction, cbyte uint16) bool {
 	opCode := uint8(cbyte >> 3 & 0x07)
 	reg := c.cbRegLookup(cbyte & 0x07)
 
-	if reg == RegisterTypeHL {
-		c.ctx.EmuCycle(2)
-	}
-
 	var zflag, cflag, final uint8
 	rbyte := c.cbReadRegister(reg)
 
@@ -46,7 +40,7 @@ func (c *Cpu) execCB(_ CpuInstriction, cbyte uint16) bool {
 	}
 
 	if reg == RegisterTypeHL {
-		c.ctx.Bus.Write(c.readFromRegister(RegisterTypeHL), final)
+		c.write(c.readFromRegister(RegisterTypeHL), final)
 	} else {
 		c.writeToRegister(reg, uint16(final))
 	}
@@ -64,10 +58,6 @@ func (c *Cpu) execCB_BitOp(bitOp uint8, cbyte uint16) {
 	idx := uint8(cbyte >> 3 & 0x07)
 	reg := c.cbRegLookup(cbyte & 0x07)
 
-	if reg == RegisterTypeHL {
-		c.ctx.EmuCycle(2)
-	}
-
 	switch bitOp {
 	case 0x1: // BIT
 		var zflag uint8
@@ -113,7 +103,7 @@ func (c *Cpu) cbReadRegister(reg RegisterType) uint8 {
 	case RegisterTypeL:
 		return c.registers.L
 	case RegisterTypeHL:
-		return c.ctx.Bus.Read(c.readFromRegister(RegisterTypeHL))
+		return c.read(c.readFromRegister(RegisterTypeHL))
 	default:
 		return 0
 	}
@@ -136,6 +126,6 @@ func (c *Cpu) cbWriteRegister(reg RegisterType, val uint8) {
 	case RegisterTypeL:
 		c.registers.L = val
 	case RegisterTypeHL:
-		c.ctx.Bus.Write(c.readFromRegister(RegisterTypeHL), val)
+		c.write(c.readFromRegister(RegisterTypeHL), val)
 	}
 }
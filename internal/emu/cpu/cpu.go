@@ -13,6 +13,14 @@ type Cpu struct {
 	registers *cpuRegisters
 
 	halted bool
+	// set when HALT is executed with IME off and an interrupt pending, the next opcode fetch
+	// fails to increment PC so the following byte is read twice
+	haltBug bool
+	stopped bool
+
+	// cgb speed switching
+	doubleSpeed      bool
+	speedSwitchArmed bool
 
 	// interrupts
 	ime               bool
@@ -20,24 +28,49 @@ type Cpu struct {
 	interruptFlags    uint8
 	interruptRegister uint8
 
+	// M-cycles already ticked by bus accesses in the current step
+	stepCycles uint8
+
 	ctx *context.Context
 }
 
 func New(ctx *context.Context) {
-	ctx.Cpu = &Cpu{
-		registers: &cpuRegisters{
+	registers := &cpuRegisters{
+		PC: 0x100,
+		SP: 0xFFFE,
+		A:  0x01,
+		F:  0xB0,
+		B:  0x00,
+		C:  0x13,
+		D:  0x00,
+		E:  0xD8,
+		H:  0x01,
+		L:  0x4D,
+	}
+
+	if ctx.CgbMode {
+		registers = &cpuRegisters{
 			PC: 0x100,
 			SP: 0xFFFE,
-			A:  0x01,
-			F:  0xB0,
+			A:  0x11,
+			F:  0x80,
 			B:  0x00,
-			C:  0x13,
-			D:  0x00,
-			E:  0xD8,
-			H:  0x01,
-			L:  0x4D,
-		},
-		ctx: ctx,
+			C:  0x00,
+			D:  0xFF,
+			E:  0x56,
+			H:  0x00,
+			L:  0x0D,
+		}
+	}
+
+	// NB: the boot rom is responsible for setting up the post boot register values
+	if ctx.BootRom != nil {
+		registers = &cpuRegisters{}
+	}
+
+	ctx.Cpu = &Cpu{
+		registers: registers,
+		ctx:       ctx,
 	}
 }
 
@@ -60,6 +93,12 @@ func (c *Cpu) LoadState(data []byte) {
 	binary.Read(r, binary.BigEndian, &c.registers.L)
 	binary.Read(r, binary.BigEndian, &c.registers.SP)
 	binary.Read(r, binary.BigEndian, &c.registers.PC)
+
+	binary.Read(r, binary.BigEndian, &c.doubleSpeed)
+	binary.Read(r, binary.BigEndian, &c.speedSwitchArmed)
+
+	binary.Read(r, binary.BigEndian, &c.haltBug)
+	binary.Read(r, binary.BigEndian, &c.stopped)
 }
 
 func (c *Cpu) SaveState() []byte {
@@ -82,6 +121,12 @@ func (c *Cpu) SaveState() []byte {
 	binary.Write(&buf, binary.BigEndian, c.registers.SP)
 	binary.Write(&buf, binary.BigEndian, c.registers.PC)
 
+	binary.Write(&buf, binary.BigEndian, c.doubleSpeed)
+	binary.Write(&buf, binary.BigEndian, c.speedSwitchArmed)
+
+	binary.Write(&buf, binary.BigEndian, c.haltBug)
+	binary.Write(&buf, binary.BigEndian, c.stopped)
+
 	return buf.Bytes()
 }
 
@@ -125,13 +170,79 @@ func (c *Cpu) String(pc uint16) string {
 	)
 }
 
+func (c *Cpu) DoubleSpeed() bool {
+	return c.doubleSpeed
+}
+
+// Key1 reads the cgb speed switch register
+// Bit 7: Current speed (Read-only): 0 = Normal, 1 = Double
+// Bit 0: Switch armed:              0 = No, 1 = Armed
+func (c *Cpu) Key1() uint8 {
+	if !c.ctx.CgbMode {
+		return 0xFF
+	}
+
+	value := uint8(0x7E)
+	if c.doubleSpeed {
+		value |= 0x80
+	}
+	if c.speedSwitchArmed {
+		value |= 0x01
+	}
+
+	return value
+}
+
+func (c *Cpu) SetKey1(value uint8) {
+	if !c.ctx.CgbMode {
+		return
+	}
+
+	c.speedSwitchArmed = value&0x01 == 0x01
+}
+
 func (c *Cpu) Step() error {
-	if c.halted {
+	// NB: the cpu is stalled while the cgb vram dma is copying a block
+	if c.ctx.Hdma.Transferring() {
 		c.ctx.EmuCycle(1)
-		if c.interruptFlags != 0 {
+		return nil
+	}
+
+	// NB: interrupts are checked between instructions, the dispatch replaces the next instruction
+	if c.ime && !c.stopped && c.pendingInterrupts() != 0 {
+		c.dispatchInterrupt()
+		return nil
+	}
+
+	// NB: EI takes effect after the instruction that follows it, so IME is set here and the
+	//     interrupt check happens at the start of the next step
+	if c.enablingIME {
+		c.ime = true
+		c.enablingIME = false
+	}
+
+	switch {
+	case c.stopped:
+		// NB: only a button press wakes the cpu from stop, DIV is held in reset until then so the
+		//     timer can't count. the ppu is left running so frames stay paced
+		c.ctx.EmuCycle(1)
+		c.ctx.Bus.Write(0xFF04, 0x00)
+
+		if c.joypadLineLow() {
+			c.stopped = false
+		}
+
+	case c.halted:
+		c.ctx.EmuCycle(1)
+
+		// NB: halt ends as soon as an enabled interrupt is requested, even when IME is off
+		if c.pendingInterrupts() != 0 {
 			c.halted = false
 		}
-	} else {
+
+	default:
+		c.stepCycles = 0
+
 		pc := c.registers.PC
 		_, instruction := c.fetchIsntruction()
 		data, destAddress := c.fetchData(instruction)
@@ -142,43 +253,73 @@ func (c *Cpu) Step() error {
 			c.ctx.Debug.Print()
 		}
 
+		cycles := instruction.CyclesUntaken
 		if c.executeInstruction(instruction, data, destAddress) {
-			c.ctx.EmuCycle(instruction.CyclesTaken)
-		} else {
-			c.ctx.EmuCycle(instruction.CyclesUntaken)
+			cycles = instruction.CyclesTaken
 		}
-	}
 
-	if c.ime {
-		c.handleInterrupts()
-		c.enablingIME = false
-	}
-
-	if c.enablingIME {
-		c.ime = true
+		// NB: bus accesses tick as they happen, whatever is left are internal cycles
+		if c.stepCycles < cycles {
+			c.ctx.EmuCycle(cycles - c.stepCycles)
+		}
 	}
 
 	return nil
 }
 
+// tick advances the rest of the system by one M-cycle on behalf of the current instruction
+func (c *Cpu) tick() {
+	c.ctx.EmuCycle(1)
+	c.stepCycles++
+}
+
+// read takes one M-cycle, the rest of the system is ticked before the access happens
+func (c *Cpu) read(address uint16) uint8 {
+	c.tick()
+	return c.ctx.Bus.Read(address)
+}
+
+func (c *Cpu) read16(address uint16) uint16 {
+	return uint16(c.read(address)) | uint16(c.read(address+1))<<8
+}
+
+// write takes one M-cycle, the rest of the system is ticked before the access happens
+func (c *Cpu) write(address uint16, value uint8) {
+	c.tick()
+	c.ctx.Bus.Write(address, value)
+}
+
+func (c *Cpu) write16(address uint16, value uint16) {
+	c.write(address, uint8(value))
+	c.write(address+1, uint8(value>>8))
+}
+
 func (c *Cpu) stackPop() uint16 {
-	val := c.ctx.Bus.Read16(c.registers.SP)
+	val := c.read16(c.registers.SP)
 	c.registers.SP += 2
 
 	return val
 }
 
 func (c *Cpu) stackPush(value uint16) {
+	// NB: every push is preceded by an internal cycle to decrement SP
+	c.tick()
+
 	c.registers.SP--
-	c.ctx.Bus.Write(c.registers.SP, uint8(value>>8))
+	c.write(c.registers.SP, uint8(value>>8))
 
 	c.registers.SP--
-	c.ctx.Bus.Write(c.registers.SP, uint8(value))
+	c.write(c.registers.SP, uint8(value))
 }
 
 func (c *Cpu) fetchIsntruction() (uint8, CpuInstriction) {
-	opcode := c.ctx.Bus.Read(c.registers.PC)
-	c.registers.PC++
+	opcode := c.read(c.registers.PC)
+
+	if c.haltBug {
+		c.haltBug = false
+	} else {
+		c.registers.PC++
+	}
 
 	return opcode, CpuInstructions[opcode]
 }
@@ -198,7 +339,7 @@ func (c *Cpu) fetchData(instruction CpuInstriction) (data uint16, destAddr *CpuD
 	case AddressModeR_N16,
 		AddressModeN16:
 
-		data = c.ctx.Bus.Read16(c.registers.PC)
+		data = c.read16(c.registers.PC)
 		c.registers.PC += 2
 
 	case AddressModeHL_SPR,
@@ -206,7 +347,7 @@ func (c *Cpu) fetchData(instruction CpuInstriction) (data uint16, destAddr *CpuD
 		AddressModeR_N8,
 		AddressModeN8:
 
-		data = uint16(c.ctx.Bus.Read(c.registers.PC))
+		data = uint16(c.read(c.registers.PC))
 		c.registers.PC++
 
 	case AddressModeMR_R:
@@ -221,29 +362,29 @@ func (c *Cpu) fetchData(instruction CpuInstriction) (data uint16, destAddr *CpuD
 		if instruction.Register2 == RegisterTypeC {
 			address |= 0xFF00
 		}
-		data = uint16(c.ctx.Bus.Read(address))
+		data = uint16(c.read(address))
 
 	case AddressModeA8_R:
-		destAddr = &CpuDestAddress{uint16(c.ctx.Bus.Read(c.registers.PC)) | 0xFF00}
+		destAddr = &CpuDestAddress{uint16(c.read(c.registers.PC)) | 0xFF00}
 		c.registers.PC++
 
 	case AddressModeMR:
 		destAddr = &CpuDestAddress{c.readFromRegister(instruction.Register1)}
-		data = uint16(c.ctx.Bus.Read(c.readFromRegister(instruction.Register1)))
+		data = uint16(c.read(c.readFromRegister(instruction.Register1)))
 
 	case AddressModeMR_N8:
 		destAddr = &CpuDestAddress{c.readFromRegister(instruction.Register1)}
-		data = uint16(c.ctx.Bus.Read(c.registers.PC))
+		data = uint16(c.read(c.registers.PC))
 		c.registers.PC++
 
 	case AddressModeR_HLI:
 		hl := c.readFromRegister(RegisterTypeHL)
-		data = uint16(c.ctx.Bus.Read(hl))
+		data = uint16(c.read(hl))
 		c.writeToRegister(RegisterTypeHL, hl+1)
 
 	case AddressModeR_HLD:
 		hl := c.readFromRegister(RegisterTypeHL)
-		data = uint16(c.ctx.Bus.Read(hl))
+		data = uint16(c.read(hl))
 		c.writeToRegister(RegisterTypeHL, hl-1)
 
 	case AddressModeHLI_R:
@@ -260,15 +401,15 @@ func (c *Cpu) fetchData(instruction CpuInstriction) (data uint16, destAddr *CpuD
 
 	case AddressModeA16_R:
 		destAddr = &CpuDestAddress{
-			c.ctx.Bus.Read16(c.registers.PC),
+			c.read16(c.registers.PC),
 		}
 		c.registers.PC += 2
 		data = c.readFromRegister(instruction.Register2)
 
 	case AddressModeR_A16:
-		addr := c.ctx.Bus.Read16(c.registers.PC)
+		addr := c.read16(c.registers.PC)
 		c.registers.PC += 2
-		data = uint16(c.ctx.Bus.Read(addr))
+		data = uint16(c.read(addr))
 	}
 
 	return data, destAddr
@@ -0,0 +1,285 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/indeedhat/gb-emulator/internal/emu/context"
+	. "github.com/indeedhat/gb-emulator/internal/emu/enum"
+	. "github.com/indeedhat/gb-emulator/internal/emu/types"
+)
+
+// stubBus is a flat 64k address space with IE and IF routed to the cpu
+type stubBus struct {
+	mem [0x10000]uint8
+	ctx *context.Context
+}
+
+func (b *stubBus) Read(address uint16) uint8 {
+	switch address {
+	case 0xFF0F:
+		return b.ctx.Cpu.InterruptFlags()
+	case 0xFFFF:
+		return b.ctx.Cpu.InterruptRegister()
+	}
+
+	return b.mem[address]
+}
+
+func (b *stubBus) Write(address uint16, value uint8) {
+	switch address {
+	case 0xFF0F:
+		b.ctx.Cpu.SetInterruptFlags(value)
+	case 0xFFFF:
+		b.ctx.Cpu.SetInterruptRegister(value)
+	default:
+		b.mem[address] = value
+	}
+}
+
+func (b *stubBus) Read16(address uint16) uint16 {
+	return uint16(b.Read(address)) | uint16(b.Read(address+1))<<8
+}
+
+func (b *stubBus) Write16(address, value uint16) {
+	b.Write(address, uint8(value))
+	b.Write(address+1, uint8(value>>8))
+}
+
+// stubDevice stands in for every other component ticked by EmuCycle
+type stubDevice struct{}
+
+func (stubDevice) Tick()                         {}
+func (stubDevice) Read(address uint16) uint8     { return 0xFF }
+func (stubDevice) Write(address uint16, _ uint8) {}
+func (stubDevice) Active() bool                  { return false }
+func (stubDevice) Start(value uint8)             {}
+func (stubDevice) Transferring() bool            { return false }
+func (stubDevice) Hblank()                       {}
+func (stubDevice) Connect(cable LinkCable)       {}
+func (stubDevice) Update()                       {}
+func (stubDevice) Print()                        {}
+func (stubDevice) Enabled() bool                 { return false }
+
+// newTestCpu creates a cpu in the post boot dmg state with the program loaded at 0x100
+func newTestCpu(program ...uint8) (*Cpu, *stubBus, *context.Context) {
+	ctx := &context.Context{}
+	bus := &stubBus{ctx: ctx}
+	copy(bus.mem[0x100:], program)
+
+	ctx.Bus = bus
+	ctx.Io = stubDevice{}
+	ctx.Timer = stubDevice{}
+	ctx.Ppu = stubDevice{}
+	ctx.Apu = stubDevice{}
+	ctx.Dma = stubDevice{}
+	ctx.Hdma = stubDevice{}
+	ctx.Serial = stubDevice{}
+	ctx.Debug = stubDevice{}
+
+	New(ctx)
+
+	return ctx.Cpu.(*Cpu), bus, ctx
+}
+
+func step(t *testing.T, c *Cpu, n int) {
+	t.Helper()
+
+	for range n {
+		if err := c.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+const (
+	opNop  = 0x00
+	opIncA = 0x3C
+	opHalt = 0x76
+	opDi   = 0xF3
+	opEi   = 0xFB
+)
+
+func TestHaltBug(t *testing.T) {
+	cases := []struct {
+		name    string
+		pending bool
+		wantPC  uint16
+		wantA   uint8
+	}{
+		{name: "ime off with interrupt pending repeats the next byte", pending: true, wantPC: 0x102, wantA: 2},
+		{name: "ime off without interrupt halts", wantPC: 0x101, wantA: 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _, _ := newTestCpu(opHalt, opIncA, opNop)
+			c.registers.A = 0
+			if tc.pending {
+				c.interruptRegister = InterruptTimer
+				c.interruptFlags = InterruptTimer
+			}
+
+			step(t, c, 3)
+
+			if c.registers.PC != tc.wantPC {
+				t.Fatalf("PC = 0x%04X, want 0x%04X", c.registers.PC, tc.wantPC)
+			}
+			if c.registers.A != tc.wantA {
+				t.Fatalf("A = %d, want %d", c.registers.A, tc.wantA)
+			}
+		})
+	}
+}
+
+func TestHaltWake(t *testing.T) {
+	cases := []struct {
+		name       string
+		ie         uint8
+		iflags     uint8
+		wantHalted bool
+	}{
+		{name: "nothing requested", ie: 0x1F, iflags: 0x00, wantHalted: true},
+		{name: "requested but not enabled", ie: InterruptVBlank, iflags: InterruptTimer, wantHalted: true},
+		{name: "enabled and requested", ie: InterruptTimer, iflags: InterruptTimer, wantHalted: false},
+		{name: "upper IF bits are ignored", ie: 0xE0, iflags: 0xE0, wantHalted: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _, _ := newTestCpu(opHalt, opNop)
+			step(t, c, 1)
+
+			if !c.halted {
+				t.Fatal("cpu did not halt")
+			}
+
+			c.interruptRegister = tc.ie
+			c.interruptFlags = tc.iflags
+			step(t, c, 1)
+
+			if c.halted != tc.wantHalted {
+				t.Fatalf("halted = %t, want %t", c.halted, tc.wantHalted)
+			}
+
+			// NB: with IME off the cpu carries on after halt rather than dispatching
+			if !tc.wantHalted && c.registers.PC != 0x101 {
+				t.Fatalf("PC = 0x%04X, want 0x0101", c.registers.PC)
+			}
+		})
+	}
+}
+
+func TestInterruptDispatch(t *testing.T) {
+	cases := []struct {
+		name       string
+		pc         uint16
+		sp         uint16
+		pending    uint8
+		wantPC     uint16
+		wantIF     uint8
+		wantCycles uint64
+	}{
+		{
+			name:       "vblank",
+			pc:         0x0100,
+			sp:         0xFFFE,
+			pending:    InterruptVBlank,
+			wantPC:     0x0040,
+			wantIF:     0x00,
+			wantCycles: 5,
+		},
+		{
+			name:       "highest priority is dispatched first",
+			pc:         0x0100,
+			sp:         0xFFFE,
+			pending:    InterruptTimer | InterruptSerial,
+			wantPC:     0x0050,
+			wantIF:     InterruptSerial,
+			wantCycles: 5,
+		},
+		{
+			// NB: the upper byte of PC (0x01) lands in IE, vblank is still enabled so is dispatched
+			name:       "high byte push leaves the interrupt enabled",
+			pc:         0x0100,
+			sp:         0x0000,
+			pending:    InterruptVBlank,
+			wantPC:     0x0040,
+			wantIF:     0x00,
+			wantCycles: 5,
+		},
+		{
+			// NB: the upper byte of PC (0x01) lands in IE, disabling the timer before it is chosen
+			name:       "high byte push cancels the interrupt",
+			pc:         0x0100,
+			sp:         0x0000,
+			pending:    InterruptTimer,
+			wantPC:     0x0000,
+			wantIF:     InterruptTimer,
+			wantCycles: 5,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, bus, ctx := newTestCpu()
+			c.registers.PC = tc.pc
+			c.registers.SP = tc.sp
+			c.ime = true
+			c.interruptRegister = tc.pending
+			c.interruptFlags = tc.pending
+
+			before := ctx.Ticks()
+			step(t, c, 1)
+
+			if cycles := (ctx.Ticks() - before) / 4; cycles != tc.wantCycles {
+				t.Fatalf("dispatch took %d M-cycles, want %d", cycles, tc.wantCycles)
+			}
+			if c.registers.PC != tc.wantPC {
+				t.Fatalf("PC = 0x%04X, want 0x%04X", c.registers.PC, tc.wantPC)
+			}
+			if c.interruptFlags != tc.wantIF {
+				t.Fatalf("IF = 0x%02X, want 0x%02X", c.interruptFlags, tc.wantIF)
+			}
+			if c.ime {
+				t.Fatal("IME still set after dispatch")
+			}
+
+			sp := tc.sp - 2
+			if ret := uint16(bus.mem[sp]) | uint16(bus.Read(sp+1))<<8; ret != tc.pc {
+				t.Fatalf("pushed return address 0x%04X, want 0x%04X", ret, tc.pc)
+			}
+		})
+	}
+}
+
+func TestEiDelay(t *testing.T) {
+	cases := []struct {
+		name    string
+		program []uint8
+		steps   int
+		wantPC  uint16
+		wantIME bool
+	}{
+		{name: "ime is still off after EI", program: []uint8{opEi, opNop, opNop}, steps: 1, wantPC: 0x101},
+		{name: "the instruction after EI runs first", program: []uint8{opEi, opNop, opNop}, steps: 2, wantPC: 0x102, wantIME: true},
+		{name: "interrupt dispatched after the next instruction", program: []uint8{opEi, opNop, opNop}, steps: 3, wantPC: 0x040},
+		{name: "EI DI never enables interrupts", program: []uint8{opEi, opDi, opNop, opNop}, steps: 4, wantPC: 0x104},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _, _ := newTestCpu(tc.program...)
+			c.interruptRegister = InterruptVBlank
+			c.interruptFlags = InterruptVBlank
+
+			step(t, c, tc.steps)
+
+			if c.registers.PC != tc.wantPC {
+				t.Fatalf("PC = 0x%04X, want 0x%04X", c.registers.PC, tc.wantPC)
+			}
+			if c.ime != tc.wantIME {
+				t.Fatalf("IME = %t, want %t", c.ime, tc.wantIME)
+			}
+		})
+	}
+}
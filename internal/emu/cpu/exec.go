@@ -39,9 +39,9 @@ func (c *Cpu) execEI() bool {
 func (c *Cpu) execLD(instruction CpuInstriction, data uint16, destAddress *CpuDestAddress) bool {
 	if nil != destAddress {
 		if instruction.Register2.Is16bit() {
-			c.ctx.Bus.Write16(destAddress.Address, data)
+			c.write16(destAddress.Address, data)
 		} else {
-			c.ctx.Bus.Write(destAddress.Address, uint8(data&0xFF))
+			c.write(destAddress.Address, uint8(data&0xFF))
 		}
 
 		goto done
@@ -73,9 +73,9 @@ done:
 
 func (c *Cpu) execLDH(instruction CpuInstriction, data uint16, destAddress *CpuDestAddress) bool {
 	if instruction.Register1 == RegisterTypeA {
-		c.writeToRegister(RegisterTypeA, uint16(c.ctx.Bus.Read(0xFF00|data)))
+		c.writeToRegister(RegisterTypeA, uint16(c.read(0xFF00|data)))
 	} else {
-		c.ctx.Bus.Write(destAddress.Address, c.registers.A)
+		c.write(destAddress.Address, c.registers.A)
 	}
 
 	return true
@@ -94,7 +94,7 @@ func (c *Cpu) execINC(instruction CpuInstriction, data uint16, destAddress *CpuD
 	hflag := halfCarry(data-1, 1, data)
 
 	if nil != destAddress {
-		c.ctx.Bus.Write(destAddress.Address, uint8(data))
+		c.write(destAddress.Address, uint8(data))
 	} else {
 		c.writeToRegister(instruction.Register1, data)
 	}
@@ -119,7 +119,7 @@ func (c *Cpu) execDEC(instruction CpuInstriction, data uint16, destAddress *CpuD
 	hflag := halfCarry(data+1, 1, data)
 
 	if nil != destAddress {
-		c.ctx.Bus.Write(destAddress.Address, uint8(data))
+		c.write(destAddress.Address, uint8(data))
 	} else {
 		c.writeToRegister(instruction.Register1, data)
 	}
@@ -351,6 +351,11 @@ func (c *Cpu) execPUSH(Instruction CpuInstriction) bool {
 }
 
 func (c *Cpu) execRET(instruction CpuInstriction) bool {
+	// NB: conditional returns spend an internal cycle checking the flag before popping
+	if instruction.Condition != ConditionTypeNone {
+		c.tick()
+	}
+
 	if !c.registers.CheckFlag(instruction.Condition) {
 		return false
 	}
@@ -413,13 +418,38 @@ func (c *Cpu) execSCF() bool {
 }
 
 func (c *Cpu) execSTOP(_ uint16) bool {
+	if !c.ctx.CgbMode || !c.speedSwitchArmed {
+		// NB: with a button already held the cpu never enters stop mode
+		if !c.joypadLineLow() {
+			c.stopped = true
+		}
+
+		c.ctx.Bus.Write(0xFF04, 0x00)
+		return true
+	}
+
+	c.doubleSpeed = !c.doubleSpeed
+	c.speedSwitchArmed = false
+
 	// writing to the timers div register resets it
-	// c.ctx.membus.Write(0xFF04, 0x01)
-	// panic("stop not implemented")
+	c.ctx.Bus.Write(0xFF04, 0x00)
+
 	return true
 }
 
 func (c *Cpu) execHALT() bool {
+	// NB: the halt bug, with IME off and an interrupt already pending halt is skipped
+	//     and the next opcode fetch doesn't increment PC
+	if !c.ime && c.pendingInterrupts() != 0 {
+		c.haltBug = true
+		return true
+	}
+
 	c.halted = true
 	return true
 }
+
+// joypadLineLow reports if any button in the currently selected joypad row is held
+func (c *Cpu) joypadLineLow() bool {
+	return c.ctx.Io.Read(0xFF00)&0x0F != 0x0F
+}
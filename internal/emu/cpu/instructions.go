@@ -345,7 +345,7 @@ var CpuInstructions = []CpuInstriction{
 	0xC8: {InstructionTypeRET, 5, 2, AddressModeNone, RegisterTypeNone, RegisterTypeNone, ConditionTypeZ, 0},
 	0xC9: {InstructionTypeRET, 4, 4, AddressModeNone, RegisterTypeNone, RegisterTypeNone, ConditionTypeNone, 0},
 	0xCA: {InstructionTypeJP, 4, 3, AddressModeN16, RegisterTypeNone, RegisterTypeNone, ConditionTypeZ, 0},
-	0xCB: {InstructionTypeCB, 1, 1, AddressModeN8, RegisterTypeNone, RegisterTypeNone, ConditionTypeNone, 0},
+	0xCB: {InstructionTypeCB, 2, 2, AddressModeN8, RegisterTypeNone, RegisterTypeNone, ConditionTypeNone, 0},
 	0xCC: {InstructionTypeCALL, 6, 3, AddressModeN16, RegisterTypeNone, RegisterTypeNone, ConditionTypeZ, 0},
 	0xCD: {InstructionTypeCALL, 6, 6, AddressModeN16, RegisterTypeNone, RegisterTypeNone, ConditionTypeNone, 0},
 	0xCE: {InstructionTypeADC, 2, 2, AddressModeR_N8, RegisterTypeA, RegisterTypeNone, ConditionTypeNone, 0},
@@ -1,6 +1,6 @@
 package cpu
 
-import . "github.com/indeedhat/gb-emulator/internal/emu/enum"
+const interruptMask = uint8(0x1F)
 
 func (c *Cpu) RequestInterrupt(itype uint8) {
 	c.interruptFlags |= itype
@@ -22,24 +22,53 @@ func (c *Cpu) SetInterruptRegister(value uint8) {
 	c.interruptRegister = value
 }
 
-func (c *Cpu) handleInterrupts() {
-	_ = c.attempInterrupt(InterruptVBlank, 0x40) ||
-		c.attempInterrupt(InterruptLcdStat, 0x48) ||
-		c.attempInterrupt(InterruptTimer, 0x50) ||
-		c.attempInterrupt(InterruptSerial, 0x58) ||
-		c.attempInterrupt(InterruptJoyPad, 0x60)
+// pendingInterrupts are the interrupts that are both requested and enabled in IE
+func (c *Cpu) pendingInterrupts() uint8 {
+	return c.interruptFlags & c.interruptRegister & interruptMask
 }
 
-func (c *Cpu) attempInterrupt(interrupt uint8, addr uint16) bool {
-	if c.interruptFlags&c.interruptRegister&interrupt == 0 {
-		return false
+// nextInterrupt finds the highest priority pending interrupt and its vector
+//
+// when nothing is pending the vector is 0x0000
+func (c *Cpu) nextInterrupt() (uint8, uint16) {
+	pending := c.pendingInterrupts()
+
+	for bit := range 5 {
+		interrupt := uint8(1) << bit
+		if pending&interrupt != 0 {
+			return interrupt, 0x40 + uint16(bit)*8
+		}
 	}
 
-	c.stackPush(c.registers.PC)
-	c.registers.PC = addr
-	c.interruptFlags &= ^interrupt
-	c.halted = false
+	return 0, 0x0000
+}
+
+// dispatchInterrupt jumps to the highest priority pending interrupt, it takes 5 M-cycles
+//
+// - 2 idle cycles
+// - push the upper byte of PC
+// - push the lower byte of PC
+// - jump to the vector
+//
+// the interrupt is only chosen after the upper byte has been pushed, if that push overwrote IE
+// (SP was 0x0000) the dispatch can switch to a different interrupt or be cancelled entirely,
+// in which case the cpu jumps to 0x0000 instead
+func (c *Cpu) dispatchInterrupt() {
 	c.ime = false
+	c.halted = false
+
+	c.tick()
+	c.tick()
+
+	c.registers.SP--
+	c.write(c.registers.SP, uint8(c.registers.PC>>8))
+
+	interrupt, vector := c.nextInterrupt()
+
+	c.registers.SP--
+	c.write(c.registers.SP, uint8(c.registers.PC))
 
-	return true
+	c.interruptFlags &^= interrupt
+	c.registers.PC = vector
+	c.tick()
 }
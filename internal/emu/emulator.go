@@ -1,11 +1,14 @@
 package emu
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"path"
+	"sync/atomic"
 	"time"
 
+	"github.com/indeedhat/gb-emulator/internal/emu/apu"
 	"github.com/indeedhat/gb-emulator/internal/emu/cart"
 	"github.com/indeedhat/gb-emulator/internal/emu/context"
 	"github.com/indeedhat/gb-emulator/internal/emu/cpu"
@@ -18,15 +21,34 @@ import (
 	. "github.com/indeedhat/gb-emulator/internal/emu/types"
 )
 
+// BootRoms holds the paths of the boot roms to run before the cart
+// if the path for the current hardware mode is empty the boot sequence is skipped
+type BootRoms struct {
+	Dmg string
+	Cgb string
+}
+
 type Emulator struct {
 	running bool
 	paused  bool
 
+	audioSink AudioSink
+	audioStop chan struct{}
+	audioDone chan struct{}
+	linkCable LinkCable
+
+	// closed when Run returns
+	runDone chan struct{}
+
+	// set once a second by scheduleBatterySaves, the save itself happens on the emulation thread
+	// so the cart ram is never read while the cpu is writing to it
+	saveDue atomic.Bool
+
 	ctx *context.Context
 }
 
-func NewEmulator(romPath string, debugEnabled bool) (*Emulator, *context.Context, error) {
-	e := &Emulator{}
+func NewEmulator(romPath string, bootRoms BootRoms, debugEnabled bool) (*Emulator, *context.Context, error) {
+	e := &Emulator{runDone: make(chan struct{})}
 
 	cartridge, err := cart.Load(romPath)
 	if err != nil {
@@ -35,6 +57,12 @@ func NewEmulator(romPath string, debugEnabled bool) (*Emulator, *context.Context
 
 	e.ctx = context.NewContext()
 	e.ctx.Cart = cartridge
+	e.ctx.CgbMode = cartridge.Header().IsCgb()
+	e.ctx.CartClock, _ = cartridge.Mbc().(Ticker)
+
+	if e.ctx.BootRom, err = loadBootRom(bootRoms, e.ctx.CgbMode); err != nil {
+		return nil, nil, err
+	}
 
 	memory.NewBus(e.ctx)
 	cpu.New(e.ctx)
@@ -44,15 +72,56 @@ func NewEmulator(romPath string, debugEnabled bool) (*Emulator, *context.Context
 	ppu.New(e.ctx)
 	ppu.NewPixelFetcher(e.ctx)
 	ppu.NewDma(e.ctx)
+	ppu.NewHdma(e.ctx)
 	lcd.New(e.ctx)
+	apu.New(e.ctx)
+
+	go e.forwardTilt()
 
 	return e, e.ctx, nil
 }
 
+// loadBootRom loads the boot rom for the current hardware mode, nil is returned if there is none
+func loadBootRom(bootRoms BootRoms, cgb bool) ([]byte, error) {
+	path, size := bootRoms.Dmg, 0x100
+	if cgb {
+		path, size = bootRoms.Cgb, 0x900
+	}
+
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) != size {
+		return nil, fmt.Errorf("boot rom %s is %d bytes, expected %d", path, len(data), size)
+	}
+
+	return data, nil
+}
+
+// forwardTilt passes tilt input through to the cart if it has an accelerometer
+//
+// the channel is always drained so input sources don't block when the cart has no sensor
+func (e *Emulator) forwardTilt() {
+	sensor, _ := e.ctx.Cart.Mbc().(Accelerometer)
+
+	for event := range e.ctx.TiltCh {
+		if sensor != nil {
+			sensor.SetTilt(event)
+		}
+	}
+}
+
 func (e *Emulator) Run() error {
 	e.running = true
+	defer close(e.runDone)
 
-	go e.saveBatteryRam()
+	go e.scheduleBatterySaves()
 
 	for e.running {
 		if e.paused {
@@ -60,6 +129,10 @@ func (e *Emulator) Run() error {
 			continue
 		}
 
+		if e.saveDue.Swap(false) {
+			e.saveBatteryRam()
+		}
+
 		if err := e.ctx.Cpu.Step(); err != nil {
 			return err
 		}
@@ -68,8 +141,101 @@ func (e *Emulator) Run() error {
 	return nil
 }
 
+// Stop ends emulation and closes anything attached to the emulator, it must only be called
+// after Run has been started
 func (e *Emulator) Stop() {
 	e.running = false
+	e.awaitRun()
+	e.saveBatteryRam()
+
+	if e.audioSink != nil {
+		close(e.audioStop)
+		<-e.audioDone
+
+		if err := e.audioSink.Close(); err != nil {
+			log.Printf("failed to close audio sink: %s", err)
+		}
+	}
+
+	if e.linkCable != nil {
+		if err := e.linkCable.Close(); err != nil {
+			log.Printf("failed to close link cable: %s", err)
+		}
+	}
+}
+
+// awaitRun waits for Run to return
+//
+// frames are discarded while waiting so a renderer that has already stopped can't block the ppu
+func (e *Emulator) awaitRun() {
+	for {
+		select {
+		case <-e.runDone:
+			return
+		case <-e.ctx.FrameCh:
+		}
+	}
+}
+
+// AttachAudioSink forwards all audio generated by the apu to the given sink
+//
+// the apu waits for the sink rather than dropping audio so recordings have no gaps
+func (e *Emulator) AttachAudioSink(sink AudioSink) {
+	e.audioSink = sink
+	e.audioStop = make(chan struct{})
+	e.audioDone = make(chan struct{})
+	e.ctx.AudioLossless = true
+
+	go e.forwardAudio(sink)
+}
+
+// forwardAudio writes audio to the sink until Stop is called
+//
+// the channel keeps being drained after a failed write so the apu is never left blocked
+func (e *Emulator) forwardAudio(sink AudioSink) {
+	defer close(e.audioDone)
+
+	failed := false
+	write := func(samples []Sample) {
+		if failed {
+			return
+		}
+
+		if err := sink.Write(samples); err != nil {
+			log.Printf("failed to write audio: %s", err)
+			failed = true
+		}
+	}
+
+	for {
+		select {
+		case samples := <-e.ctx.AudioCh:
+			write(samples)
+		case <-e.audioStop:
+			// NB: the emulator has stopped by now so whatever is buffered is the end of the audio
+			for {
+				select {
+				case samples := <-e.ctx.AudioCh:
+					write(samples)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// AttachLinkCable plugs a link cable into the serial port
+func (e *Emulator) AttachLinkCable(cable LinkCable) {
+	e.linkCable = cable
+	e.ctx.Serial.Connect(cable)
+}
+
+// AttachCameraSource sets the image seen by the sensor if the cart is a pocket camera
+func (e *Emulator) AttachCameraSource(source CameraSource) {
+	if sensor, ok := e.ctx.Cart.Mbc().(CameraSensor); ok {
+		sensor.SetCameraSource(source)
+	}
 }
 
 func (e *Emulator) Pause() {
@@ -110,27 +276,32 @@ func (e *Emulator) LoadState(path string) {
 	<-time.After(30 * time.Millisecond)
 
 	if state, err := os.ReadFile(path); err == nil {
-		e.ctx.LoadState(state)
+		if err := e.ctx.LoadState(state); err != nil {
+			log.Printf("failed to load state: %s", err)
+		}
 	}
 }
 
-func (e *Emulator) saveBatteryRam() {
+// scheduleBatterySaves flags a battery save once a second until Run returns
+func (e *Emulator) scheduleBatterySaves() {
 	ticker := time.NewTicker(1 * time.Second)
-	for range ticker.C {
-		if e.running {
-			return
-		}
-		if e.paused {
-			time.Sleep(10 * time.Millisecond)
-			continue
-		}
+	defer ticker.Stop()
 
-		if mbc3, ok := e.ctx.Cart.Mbc().(Ticker); ok {
-			mbc3.Tick()
+	for {
+		select {
+		case <-e.runDone:
+			return
+		case <-ticker.C:
+			e.saveDue.Store(true)
 		}
+	}
+}
 
-		if err := e.ctx.Cart.Save(); err != nil {
-			log.Printf("failed to save battery backed ram: %s", err)
-		}
+// saveBatteryRam writes the cart ram to disk if it has changed
+//
+// it must only be called from the emulation thread, or once Run has returned
+func (e *Emulator) saveBatteryRam() {
+	if err := e.ctx.Cart.Save(); err != nil {
+		log.Printf("failed to save battery backed ram: %s", err)
 	}
 }
@@ -0,0 +1,71 @@
+package emu
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/indeedhat/gb-emulator/internal/emu/cart"
+	. "github.com/indeedhat/gb-emulator/internal/emu/types"
+)
+
+// writeTestRom writes an empty 64 KiB rom with a valid header for the given cart type
+func writeTestRom(t *testing.T, cartType byte) string {
+	t.Helper()
+
+	data := make([]byte, 0x10000)
+	data[0x0147] = cartType
+	data[0x0148] = 0x01
+
+	var checksum uint8
+	for i := 0x0134; i < 0x014D; i++ {
+		checksum = checksum - data[i] - 1
+	}
+	data[0x014D] = checksum
+
+	path := filepath.Join(t.TempDir(), "test.gb")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestTiltInput(t *testing.T) {
+	_, ctx, err := NewEmulator(writeTestRom(t, cart.CartTypeMbc7SensorRumbleRamBattery), BootRoms{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.Cart.Write(0x0000, 0x0A)
+	ctx.Cart.Write(0x4000, 0x40)
+
+	// x = 0x81D0 + 1 * 0x70, y = 0x81D0 - 0.5 * 0x70
+	want := []uint8{0x40, 0x82, 0x98, 0x81}
+
+	ctx.TiltCh <- TiltEvent{X: 1, Y: -0.5}
+
+	// NB: the event is forwarded to the cart on another goroutine so keep latching until it lands
+	deadline := time.Now().Add(time.Second)
+	for {
+		ctx.Cart.Write(0xA000, 0x55)
+		ctx.Cart.Write(0xA010, 0xAA)
+
+		got := []uint8{
+			ctx.Cart.Read(0xA020),
+			ctx.Cart.Read(0xA030),
+			ctx.Cart.Read(0xA040),
+			ctx.Cart.Read(0xA050),
+		}
+		if slices.Equal(got, want) {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("tilt registers = % X, want % X", got, want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
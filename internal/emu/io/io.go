@@ -3,34 +3,39 @@ package io
 import "github.com/indeedhat/gb-emulator/internal/emu/context"
 
 type IO struct {
-	serial []uint8
-
 	ctx  *context.Context
 	jpad *Joypad
 }
 
 func New(ctx *context.Context) {
 	ctx.Io = &IO{
-		serial: make([]uint8, 2),
-		ctx:    ctx,
-		jpad:   newJoypad(ctx),
+		ctx:  ctx,
+		jpad: newJoypad(ctx),
 	}
+	ctx.Serial = newSerial(ctx)
 }
 
 func (i *IO) Read(addr uint16) uint8 {
 	switch true {
 	case addr == 0xFF00:
 		return i.jpad.Read(0)
-	case addr == 0xFF01:
-		return i.serial[0]
-	case addr == 0xFF02:
-		return i.serial[1]
+	case addr == 0xFF01, addr == 0xFF02:
+		return i.ctx.Serial.Read(addr)
 	case addr >= 0xFF04 && addr <= 0xFF07:
 		return i.ctx.Timer.Read(addr)
 	case addr == 0xFF0F:
 		return i.ctx.Cpu.InterruptFlags()
-	case addr >= 0xFF40 && addr <= 0xFF4B:
+	case addr >= 0xFF10 && addr <= 0xFF3F:
+		return i.ctx.Apu.Read(addr)
+	case addr >= 0xFF40 && addr <= 0xFF4B,
+		addr >= 0xFF68 && addr <= 0xFF6B:
 		return i.ctx.Lcd.Read(addr)
+	case addr == 0xFF4D:
+		return i.ctx.Cpu.Key1()
+	case addr == 0xFF4F:
+		return i.ctx.Ppu.Read(addr)
+	case addr >= 0xFF51 && addr <= 0xFF55:
+		return i.ctx.Hdma.Read(addr)
 	default:
 		// log.Printf("unsupported mem.read (IO) 0x%X", addr)
 		return 0
@@ -41,16 +46,23 @@ func (i *IO) Write(addr uint16, value uint8) {
 	switch true {
 	case addr == 0xFF00:
 		i.jpad.Write(0, value)
-	case addr == 0xFF01:
-		i.serial[0] = value
-	case addr == 0xFF02:
-		i.serial[1] = value
+	case addr == 0xFF01, addr == 0xFF02:
+		i.ctx.Serial.Write(addr, value)
 	case addr >= 0xFF04 && addr <= 0xFF07:
 		i.ctx.Timer.Write(addr, value)
 	case addr == 0xFF0F:
 		i.ctx.Cpu.SetInterruptFlags(value)
-	case addr >= 0xFF40 && addr <= 0xFF4B:
+	case addr >= 0xFF10 && addr <= 0xFF3F:
+		i.ctx.Apu.Write(addr, value)
+	case addr >= 0xFF40 && addr <= 0xFF4B,
+		addr >= 0xFF68 && addr <= 0xFF6B:
 		i.ctx.Lcd.Write(addr, value)
+	case addr == 0xFF4D:
+		i.ctx.Cpu.SetKey1(value)
+	case addr == 0xFF4F:
+		i.ctx.Ppu.Write(addr, value)
+	case addr >= 0xFF51 && addr <= 0xFF55:
+		i.ctx.Hdma.Write(addr, value)
 	default:
 		// log.Printf("unsupported mem.write (IO) 0x%X", addr)
 	}
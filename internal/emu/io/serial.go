@@ -0,0 +1,235 @@
+package io
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+
+	"github.com/indeedhat/gb-emulator/internal/emu/context"
+	. "github.com/indeedhat/gb-emulator/internal/emu/enum"
+	. "github.com/indeedhat/gb-emulator/internal/emu/types"
+)
+
+const (
+	SerialTransferEnable = uint8(1) << 7
+	SerialClockSpeed     = uint8(1) << 1
+	SerialClockInternal  = uint8(1)
+)
+
+const (
+	// M-cycles per bit with the internal clock at 8192 Hz
+	serialCyclesPerBit = 128
+	// M-cycles per bit with the cgb fast clock at 262144 Hz
+	serialCyclesPerBitFast = 4
+)
+
+// Serial implements the link port
+//
+// when using the internal clock the byte in SB is sent to the peer on a separate goroutine at the
+// start of the transfer, the reply is written to SB once all 8 bits have been shifted out (or as soon
+// as it arrives if the peer is slower than that), when using the external clock the transfer
+// completes as soon as the peer clocks a byte in
+type Serial struct {
+	sb uint8
+	sc uint8
+
+	// internal clock transfer progress
+	bitsShifted  uint8
+	bitCountdown uint16
+
+	// the peer's reply to an internal clock transfer, it arrives on the transfer goroutine
+	reply      uint8
+	replyReady bool
+	// incremented on each transfer so a late reply from an earlier transfer is ignored
+	transferId uint32
+
+	// bytes clocked in by the peer, they arrive on the cable goroutine so are applied on the next tick
+	externalPending bool
+	externalValue   uint8
+
+	cable LinkCable
+	mu    sync.Mutex
+	// held for the duration of each transfer so a cable never sees more than one at a time
+	transferMu sync.Mutex
+
+	ctx *context.Context
+}
+
+func newSerial(ctx *context.Context) *Serial {
+	return &Serial{ctx: ctx}
+}
+
+func (s *Serial) LoadState(data []byte) {
+	r := bytes.NewReader(data)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	binary.Read(r, binary.BigEndian, &s.sb)
+	binary.Read(r, binary.BigEndian, &s.sc)
+	binary.Read(r, binary.BigEndian, &s.bitsShifted)
+	binary.Read(r, binary.BigEndian, &s.bitCountdown)
+	binary.Read(r, binary.BigEndian, &s.reply)
+	binary.Read(r, binary.BigEndian, &s.replyReady)
+
+	// NB: any transfer still in flight belongs to the state being replaced
+	s.transferId++
+}
+
+func (s *Serial) SaveState() []byte {
+	var buf bytes.Buffer
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	binary.Write(&buf, binary.BigEndian, s.sb)
+	binary.Write(&buf, binary.BigEndian, s.sc)
+	binary.Write(&buf, binary.BigEndian, s.bitsShifted)
+	binary.Write(&buf, binary.BigEndian, s.bitCountdown)
+	binary.Write(&buf, binary.BigEndian, s.reply)
+	binary.Write(&buf, binary.BigEndian, s.replyReady)
+
+	return buf.Bytes()
+}
+
+func (s *Serial) Read(address uint16) uint8 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if address == 0xFF01 {
+		return s.sb
+	}
+
+	// NB: the clock speed bit only exists on the cgb
+	if s.ctx.CgbMode {
+		return s.sc | 0x7C
+	}
+
+	return s.sc | 0x7E
+}
+
+func (s *Serial) Write(address uint16, value uint8) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if address == 0xFF01 {
+		s.sb = value
+		return
+	}
+
+	s.sc = value & (SerialTransferEnable | SerialClockSpeed | SerialClockInternal)
+	if s.sc&SerialTransferEnable == 0 || s.sc&SerialClockInternal == 0 {
+		return
+	}
+
+	s.bitsShifted = 0
+	s.bitCountdown = s.cyclesPerBit()
+	s.transferId++
+
+	if s.cable == nil {
+		s.reply = 0xFF
+		s.replyReady = true
+		return
+	}
+
+	s.replyReady = false
+
+	// NB: the cable may block waiting for the peer so it must never be called on the emulation thread
+	go s.transfer(s.cable, s.sb, s.transferId)
+}
+
+// transfer exchanges a byte with the peer and stores its reply for Tick to apply
+func (s *Serial) transfer(cable LinkCable, out uint8, id uint32) {
+	s.transferMu.Lock()
+	in := cable.Transfer(out)
+	s.transferMu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id != s.transferId {
+		return
+	}
+
+	s.reply = in
+	s.replyReady = true
+}
+
+// Tick is called once per M-cycle
+func (s *Serial) Tick() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.externalPending {
+		s.externalPending = false
+
+		if s.sc&SerialTransferEnable != 0 && s.sc&SerialClockInternal == 0 {
+			s.sb = s.externalValue
+			s.complete()
+		}
+	}
+
+	if s.sc&SerialTransferEnable == 0 || s.sc&SerialClockInternal == 0 {
+		return
+	}
+
+	if s.bitsShifted < 8 {
+		s.bitCountdown--
+		if s.bitCountdown > 0 {
+			return
+		}
+
+		s.bitsShifted++
+		s.bitCountdown = s.cyclesPerBit()
+	}
+
+	// NB: if the peer has not answered yet the transfer is held open until it does
+	if s.bitsShifted == 8 && s.replyReady {
+		s.sb = s.reply
+		s.replyReady = false
+		s.complete()
+	}
+}
+
+// Connect plugs a link cable into the port, passing nil unplugs the current cable
+func (s *Serial) Connect(cable LinkCable) {
+	s.mu.Lock()
+	s.cable = cable
+	s.mu.Unlock()
+
+	if cable != nil {
+		cable.Attach(s)
+	}
+}
+
+// ExternalTransfer implements LinkPort.
+func (s *Serial) ExternalTransfer(value uint8) uint8 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// NB: the shift register only runs when a transfer has been started, otherwise nothing
+	//     drives the line and the peer reads all 1s
+	if s.sc&SerialTransferEnable == 0 || s.sc&SerialClockInternal != 0 {
+		return 0xFF
+	}
+
+	s.externalPending = true
+	s.externalValue = value
+
+	return s.sb
+}
+
+func (s *Serial) complete() {
+	s.sc &^= SerialTransferEnable
+	s.ctx.Cpu.RequestInterrupt(InterruptSerial)
+}
+
+func (s *Serial) cyclesPerBit() uint16 {
+	if s.ctx.CgbMode && s.sc&SerialClockSpeed != 0 {
+		return serialCyclesPerBitFast
+	}
+
+	return serialCyclesPerBit
+}
+
+var _ LinkPort = (*Serial)(nil)
@@ -5,9 +5,10 @@ import (
 	"encoding/binary"
 	"fmt"
 
-	"github.com/indeedhat/gb-emulator/internal/emu/config"
 	"github.com/indeedhat/gb-emulator/internal/emu/context"
 	. "github.com/indeedhat/gb-emulator/internal/emu/enum"
+	gbpalette "github.com/indeedhat/gb-emulator/internal/emu/palette"
+	. "github.com/indeedhat/gb-emulator/internal/emu/types"
 )
 
 type Lcd struct {
@@ -46,18 +47,44 @@ type Lcd struct {
 	objectPallet0    uint8
 	objectPallet1    uint8
 
+	// CGB palette ram, 8 palettes of 4 RGB555 colors
+	// Bit 7:   Auto increment on write
+	// Bit 5-0: Address
+	cgbBgPaletteIdx  uint8
+	cgbBgPalettes    []byte
+	cgbObjPaletteIdx uint8
+	cgbObjPalettes   []byte
+
+	// the STAT interrupt sources are or'ed into a single line, the interrupt is only
+	// requested on its rising edge
+	statLine bool
+
 	ctx *context.Context
 }
 
 func New(ctx *context.Context) {
-	ctx.Lcd = &Lcd{
+	l := &Lcd{
 		ctx:              ctx,
 		control:          0x91,
 		status:           uint8(LcdModeOam),
 		backgroundPallet: 0xE4,
 		objectPallet0:    0xE4,
 		objectPallet1:    0xE4,
+		cgbBgPalettes:    make([]byte, 64),
+		cgbObjPalettes:   make([]byte, 64),
+	}
+
+	for i := range l.cgbBgPalettes {
+		l.cgbBgPalettes[i] = 0xFF
+	}
+
+	// NB: the lcd is off at power on, the boot rom turns it on
+	if ctx.BootRom != nil {
+		l.control = 0x00
+		l.status = uint8(LcdModeHblank)
 	}
+
+	ctx.Lcd = l
 }
 
 func (l *Lcd) LoadState(data []byte) {
@@ -77,6 +104,13 @@ func (l *Lcd) LoadState(data []byte) {
 	binary.Read(r, binary.BigEndian, &l.backgroundPallet)
 	binary.Read(r, binary.BigEndian, &l.objectPallet0)
 	binary.Read(r, binary.BigEndian, &l.objectPallet1)
+
+	binary.Read(r, binary.BigEndian, &l.cgbBgPaletteIdx)
+	r.Read(l.cgbBgPalettes)
+	binary.Read(r, binary.BigEndian, &l.cgbObjPaletteIdx)
+	r.Read(l.cgbObjPalettes)
+
+	binary.Read(r, binary.BigEndian, &l.statLine)
 }
 
 func (l *Lcd) SaveState() []byte {
@@ -97,6 +131,13 @@ func (l *Lcd) SaveState() []byte {
 	binary.Write(&buf, binary.BigEndian, l.objectPallet0)
 	binary.Write(&buf, binary.BigEndian, l.objectPallet1)
 
+	binary.Write(&buf, binary.BigEndian, l.cgbBgPaletteIdx)
+	buf.Write(l.cgbBgPalettes)
+	binary.Write(&buf, binary.BigEndian, l.cgbObjPaletteIdx)
+	buf.Write(l.cgbObjPalettes)
+
+	binary.Write(&buf, binary.BigEndian, l.statLine)
+
 	return buf.Bytes()
 }
 
@@ -121,6 +162,7 @@ func (l *Lcd) Ly() uint8 {
 
 func (l *Lcd) ResetLy() {
 	l.ly = 0
+	l.updateStat()
 }
 
 func (l *Lcd) ScrollX() uint8 {
@@ -151,6 +193,27 @@ func (l *Lcd) SetControl(code Lcdc, set bool) {
 	}
 }
 
+// setControl writes LCDC, turning the lcd off resets LY and puts it in mode 0 until it is
+// turned back on
+func (l *Lcd) setControl(value uint8) {
+	wasOn := l.GetControl(LcdcLcdPpuEnable)
+	l.control = value
+	isOn := l.GetControl(LcdcLcdPpuEnable)
+
+	switch {
+	case wasOn && !isOn:
+		l.ly = 0
+		l.status &= ^uint8(0x3)
+
+		// NB: nothing drives the stat line while the lcd is off
+		l.statLine = false
+
+	case !wasOn && isOn:
+		l.ly = 0
+		l.SetMode(LcdModeHblank)
+	}
+}
+
 func (l *Lcd) BgTileAddress(address uint16) uint16 {
 	if l.GetControl(LcdcBgTileArea) {
 		return address + 0x9C00
@@ -187,6 +250,16 @@ func (l *Lcd) ObjectPallet(i uint8) uint8 {
 	return l.objectPallet1
 }
 
+func (l *Lcd) CgbBackgroundColor(palette, colorIdx uint8) Pixel {
+	i := (palette&0x7)*8 + (colorIdx&0x3)*2
+	return gbpalette.GetCgbColor(l.cgbBgPalettes[i], l.cgbBgPalettes[i+1])
+}
+
+func (l *Lcd) CgbObjectColor(palette, colorIdx uint8) Pixel {
+	i := (palette&0x7)*8 + (colorIdx&0x3)*2
+	return gbpalette.GetCgbColor(l.cgbObjPalettes[i], l.cgbObjPalettes[i+1])
+}
+
 func (l *Lcd) GetStatus(code LcdStatus) bool {
 	return LcdStatus(l.status)&code == code
 }
@@ -198,27 +271,43 @@ func (l *Lcd) GetMode() LcdMode {
 func (l *Lcd) SetMode(mode LcdMode) {
 	l.status &= ^uint8(0x3)
 	l.status |= uint8(mode & 0x3)
+	l.updateStat()
 }
 
 func (l *Lcd) IncrementLine() {
-	if l.ctx.Pix.WindowVisible() &&
-		l.ly >= l.windowY &&
-		l.ly < l.windowY+config.PpuYRes {
+	l.ly++
+	l.updateStat()
+}
 
-		l.ctx.Pix.IncrementWindowX()
+// updateStat refreshes the LY == LYC flag and requests the STAT interrupt if the combined
+// STAT line has gone from low to high
+//
+// it does nothing while the lcd is off, setControl re-evaluates the line when it is turned back on
+func (l *Lcd) updateStat() {
+	if !l.GetControl(LcdcLcdPpuEnable) {
+		l.statLine = false
+		return
 	}
 
-	l.ly++
-
-	if l.ly != l.lyCompare {
+	if l.ly == l.lyCompare {
+		l.status |= 0b100
+	} else {
 		l.status &= ^uint8(0b100)
-		return
 	}
 
-	l.status |= 0b100
-	if l.GetStatus(LcdStatusLyc) {
+	mode := l.GetMode()
+	line := (l.GetStatus(LcdStatusLyc) && l.status&0b100 != 0) ||
+		(l.GetStatus(LcdStatusHblank) && mode == LcdModeHblank) ||
+		(l.GetStatus(LcdStatusVblank) && mode == LcdModeVblank) ||
+		(l.GetStatus(LcdStatusOam) && mode == LcdModeOam)
+
+	// NB: as the sources share one line, a source becoming active while another is already
+	//     holding the line high does not trigger a new interrupt
+	if line && !l.statLine {
 		l.ctx.Cpu.RequestInterrupt(InterruptLcdStat)
 	}
+
+	l.statLine = line
 }
 
 func (l *Lcd) Read(addr uint16) uint8 {
@@ -226,7 +315,7 @@ func (l *Lcd) Read(addr uint16) uint8 {
 	case 0xFF40:
 		return l.control
 	case 0xFF41:
-		return l.status
+		return l.status | 0x80
 	case 0xFF42:
 		return l.scrollY
 	case 0xFF43:
@@ -247,6 +336,21 @@ func (l *Lcd) Read(addr uint16) uint8 {
 		return l.windowY
 	case 0xFF4B:
 		return l.windowX
+	}
+
+	if !l.ctx.CgbMode {
+		return 0xFF
+	}
+
+	switch addr {
+	case 0xFF68:
+		return l.cgbBgPaletteIdx | 0x40
+	case 0xFF69:
+		return l.cgbBgPalettes[l.cgbBgPaletteIdx&0x3F]
+	case 0xFF6A:
+		return l.cgbObjPaletteIdx | 0x40
+	case 0xFF6B:
+		return l.cgbObjPalettes[l.cgbObjPaletteIdx&0x3F]
 	default:
 		return 0xFF
 	}
@@ -255,9 +359,11 @@ func (l *Lcd) Read(addr uint16) uint8 {
 func (l *Lcd) Write(addr uint16, value uint8) {
 	switch addr {
 	case 0xFF40:
-		l.control = value
+		l.setControl(value)
 	case 0xFF41:
-		l.status = value
+		// NB: the mode and LY == LYC bits are read only
+		l.status = l.status&0x07 | value&0x78
+		l.updateStat()
 	case 0xFF42:
 		l.scrollY = value
 	case 0xFF43:
@@ -266,6 +372,7 @@ func (l *Lcd) Write(addr uint16, value uint8) {
 		l.ly = value
 	case 0xFF45:
 		l.lyCompare = value
+		l.updateStat()
 	case 0xFF46:
 		l.dma = value
 		l.ctx.Dma.Start(value)
@@ -280,4 +387,29 @@ func (l *Lcd) Write(addr uint16, value uint8) {
 	case 0xFF4B:
 		l.windowX = value
 	}
+
+	if !l.ctx.CgbMode {
+		return
+	}
+
+	switch addr {
+	case 0xFF68:
+		l.cgbBgPaletteIdx = value & 0xBF
+	case 0xFF69:
+		l.cgbBgPalettes[l.cgbBgPaletteIdx&0x3F] = value
+		l.cgbBgPaletteIdx = incrementPaletteIdx(l.cgbBgPaletteIdx)
+	case 0xFF6A:
+		l.cgbObjPaletteIdx = value & 0xBF
+	case 0xFF6B:
+		l.cgbObjPalettes[l.cgbObjPaletteIdx&0x3F] = value
+		l.cgbObjPaletteIdx = incrementPaletteIdx(l.cgbObjPaletteIdx)
+	}
+}
+
+func incrementPaletteIdx(idx uint8) uint8 {
+	if idx&0x80 == 0 {
+		return idx
+	}
+
+	return 0x80 | (idx+1)&0x3F
 }
@@ -0,0 +1,72 @@
+package lcd
+
+import (
+	"testing"
+
+	"github.com/indeedhat/gb-emulator/internal/emu/context"
+	. "github.com/indeedhat/gb-emulator/internal/emu/enum"
+)
+
+// stubCpu counts the STAT interrupts requested by the lcd
+type stubCpu struct {
+	statInterrupts int
+}
+
+func (c *stubCpu) RequestInterrupt(itype uint8) {
+	if itype == InterruptLcdStat {
+		c.statInterrupts++
+	}
+}
+func (c *stubCpu) Step() error                      { return nil }
+func (c *stubCpu) InterruptFlags() uint8            { return 0 }
+func (c *stubCpu) SetInterruptFlags(value uint8)    {}
+func (c *stubCpu) InterruptRegister() uint8         { return 0 }
+func (c *stubCpu) SetInterruptRegister(value uint8) {}
+func (c *stubCpu) DoubleSpeed() bool                { return false }
+func (c *stubCpu) Key1() uint8                      { return 0xFF }
+func (c *stubCpu) SetKey1(value uint8)              {}
+
+func TestStatInterrupt(t *testing.T) {
+	cases := []struct {
+		name   string
+		writes [][2]uint16
+		want   int
+	}{
+		{
+			name:   "oam source while in mode 2",
+			writes: [][2]uint16{{0xFF41, 0x20}},
+			want:   1,
+		},
+		{
+			name:   "hblank source while the lcd is off",
+			writes: [][2]uint16{{0xFF40, 0x11}, {0xFF41, 0x08}},
+			want:   0,
+		},
+		{
+			name:   "lyc source and LYC=0 while the lcd is off",
+			writes: [][2]uint16{{0xFF40, 0x11}, {0xFF41, 0x40}, {0xFF45, 0x01}, {0xFF45, 0x00}},
+			want:   0,
+		},
+		{
+			name:   "line is re-evaluated when the lcd is turned on",
+			writes: [][2]uint16{{0xFF40, 0x11}, {0xFF41, 0x08}, {0xFF40, 0x91}},
+			want:   1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cpu := &stubCpu{}
+			ctx := &context.Context{Cpu: cpu}
+			New(ctx)
+
+			for _, w := range tc.writes {
+				ctx.Lcd.Write(w[0], uint8(w[1]))
+			}
+
+			if cpu.statInterrupts != tc.want {
+				t.Fatalf("%d STAT interrupts requested, want %d", cpu.statInterrupts, tc.want)
+			}
+		})
+	}
+}
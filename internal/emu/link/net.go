@@ -0,0 +1,170 @@
+package link
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/indeedhat/gb-emulator/internal/emu/types"
+)
+
+// frame types sent over the socket, each frame is 2 bytes: type then value
+const (
+	frameTransfer = 0x01
+	frameReply    = 0x02
+)
+
+// how long to wait for the peer to answer a transfer before giving up and reading all 1s
+const replyTimeout = time.Second
+
+// NetCable is a link cable over a tcp or unix socket connection
+type NetCable struct {
+	conn net.Conn
+
+	port    LinkPort
+	replies chan uint8
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+}
+
+// Listen waits for a single peer to connect on the given address
+//
+// addresses take the form network:address, for example tcp:localhost:9000 or unix:/tmp/gb.sock
+func Listen(address string) (*NetCable, error) {
+	network, addr, err := splitAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()
+
+	conn, err := listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return newNetCable(conn), nil
+}
+
+// Dial connects to a peer waiting in Listen
+func Dial(address string) (*NetCable, error) {
+	network, addr, err := splitAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return newNetCable(conn), nil
+}
+
+func newNetCable(conn net.Conn) *NetCable {
+	c := &NetCable{
+		conn:    conn,
+		replies: make(chan uint8, 1),
+	}
+
+	go c.readLoop()
+
+	return c
+}
+
+// Transfer implements LinkCable.
+func (c *NetCable) Transfer(value uint8) uint8 {
+	// NB: drop any late reply from a transfer that previously timed out
+	select {
+	case <-c.replies:
+	default:
+	}
+
+	if err := c.write(frameTransfer, value); err != nil {
+		return 0xFF
+	}
+
+	select {
+	case reply := <-c.replies:
+		return reply
+	case <-time.After(replyTimeout):
+		return 0xFF
+	}
+}
+
+// Attach implements LinkCable.
+func (c *NetCable) Attach(port LinkPort) {
+	c.mu.Lock()
+	c.port = port
+	c.mu.Unlock()
+}
+
+// Close implements LinkCable.
+func (c *NetCable) Close() error {
+	return c.conn.Close()
+}
+
+func (c *NetCable) readLoop() {
+	frame := make([]byte, 2)
+
+	for {
+		if _, err := io.ReadFull(c.conn, frame); err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("link cable disconnected: %s", err)
+			}
+			return
+		}
+
+		switch frame[0] {
+		case frameTransfer:
+			c.mu.Lock()
+			port := c.port
+			c.mu.Unlock()
+
+			reply := uint8(0xFF)
+			if port != nil {
+				reply = port.ExternalTransfer(frame[1])
+			}
+
+			if err := c.write(frameReply, reply); err != nil {
+				log.Printf("link cable write failed: %s", err)
+				return
+			}
+
+		case frameReply:
+			select {
+			case c.replies <- frame[1]:
+			default:
+			}
+		}
+	}
+}
+
+func (c *NetCable) write(frameType, value uint8) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_, err := c.conn.Write([]byte{frameType, value})
+	return err
+}
+
+func splitAddress(address string) (string, string, error) {
+	network, addr, ok := strings.Cut(address, ":")
+	if !ok || (network != "tcp" && network != "unix") {
+		return "", "", fmt.Errorf("invalid link address %q, expected tcp:host:port or unix:/path", address)
+	}
+
+	return network, addr, nil
+}
+
+var _ LinkCable = (*NetCable)(nil)
@@ -0,0 +1,51 @@
+package link
+
+import (
+	"sync"
+
+	. "github.com/indeedhat/gb-emulator/internal/emu/types"
+)
+
+// PipeCable is one end of a link cable between two emulators in the same process
+type PipeCable struct {
+	port LinkPort
+	peer *PipeCable
+	mu   sync.Mutex
+}
+
+// NewPipe creates both ends of an in process link cable
+func NewPipe() (*PipeCable, *PipeCable) {
+	a := &PipeCable{}
+	b := &PipeCable{peer: a}
+	a.peer = b
+
+	return a, b
+}
+
+// Transfer implements LinkCable.
+func (c *PipeCable) Transfer(value uint8) uint8 {
+	c.peer.mu.Lock()
+	port := c.peer.port
+	c.peer.mu.Unlock()
+
+	if port == nil {
+		return 0xFF
+	}
+
+	return port.ExternalTransfer(value)
+}
+
+// Attach implements LinkCable.
+func (c *PipeCable) Attach(port LinkPort) {
+	c.mu.Lock()
+	c.port = port
+	c.mu.Unlock()
+}
+
+// Close implements LinkCable.
+func (c *PipeCable) Close() error {
+	c.Attach(nil)
+	return nil
+}
+
+var _ LinkCable = (*PipeCable)(nil)
@@ -2,9 +2,11 @@ package memory
 
 import (
 	"bytes"
+	"encoding/binary"
 	"log"
 
 	"github.com/indeedhat/gb-emulator/internal/emu/context"
+	. "github.com/indeedhat/gb-emulator/internal/emu/enum"
 	. "github.com/indeedhat/gb-emulator/internal/emu/types"
 )
 
@@ -23,17 +25,38 @@ import (
 
 type MemoryBus struct {
 	hram *RamBank
-	wram *RamBank
+	// bank 0 is fixed at 0xC000, all others are switched in at 0xD000
+	wram     []*RamBank
+	wramBank uint8
+
+	// the boot rom stays mapped until the program writes to 0xFF50
+	bootRom       []byte
+	bootRomMapped bool
 
 	ctx *context.Context
 }
 
 func NewBus(ctx *context.Context) {
-	ctx.Bus = &MemoryBus{
-		hram: NewRamBank(0xFF80, 0x80),
-		wram: NewRamBank(0xC000, 0x2000),
-		ctx:  ctx,
+	banks := 2
+	if ctx.CgbMode {
+		banks = 8
+	}
+
+	b := &MemoryBus{
+		hram:     NewRamBank(0xFF80, 0x80),
+		wram:     []*RamBank{NewRamBank(0xC000, 0x1000)},
+		wramBank: 1,
+		ctx:      ctx,
+
+		bootRom:       ctx.BootRom,
+		bootRomMapped: ctx.BootRom != nil,
+	}
+
+	for range banks - 1 {
+		b.wram = append(b.wram, NewRamBank(0xD000, 0x1000))
 	}
+
+	ctx.Bus = b
 }
 
 func (b *MemoryBus) LoadState(data []byte) {
@@ -43,38 +66,61 @@ func (b *MemoryBus) LoadState(data []byte) {
 	r.Read(h)
 	b.hram.Fill(h)
 
-	w := b.wram.Bytes()
-	r.Read(w)
-	b.wram.Fill(w)
+	for _, bank := range b.wram {
+		w := bank.Bytes()
+		r.Read(w)
+		bank.Fill(w)
+	}
+
+	binary.Read(r, binary.BigEndian, &b.wramBank)
+	binary.Read(r, binary.BigEndian, &b.bootRomMapped)
 
+	// NB: states saved during the boot sequence can't be resumed without the boot rom
+	if b.bootRom == nil {
+		b.bootRomMapped = false
+	}
 }
 
 func (b *MemoryBus) SaveState() []byte {
 	var buf bytes.Buffer
 
 	buf.Write(b.hram.Bytes())
-	buf.Write(b.wram.Bytes())
+	for _, bank := range b.wram {
+		buf.Write(bank.Bytes())
+	}
+
+	binary.Write(&buf, binary.BigEndian, b.wramBank)
+	binary.Write(&buf, binary.BigEndian, b.bootRomMapped)
 
 	return buf.Bytes()
 }
 
 func (b *MemoryBus) Read(address uint16) uint8 {
 	switch true {
+	case b.bootRomMapped && b.inBootRom(address):
+		return b.bootRom[address]
 	case address < 0x8000:
 		return b.ctx.Cart.Read(address)
 	case address < 0xA000:
+		if !b.vramAccessible() {
+			return 0xFF
+		}
+
 		return b.ctx.Ppu.Read(address)
 	case address < 0xC000:
 		// cart ram
 		return b.ctx.Cart.Read(address)
-	case address < 0xE000:
+	case address < 0xD000:
 		// working ram
-		return b.wram.Read(address)
+		return b.wram[0].Read(address)
+	case address < 0xE000:
+		// switchable working ram
+		return b.wram[b.wramBank].Read(address)
 	case address < 0xFE00:
 		// Echo ram is unusable
 		return 0
 	case address < 0xFEA0:
-		if b.ctx.Dma.Active() {
+		if b.ctx.Dma.Active() || !b.oamAccessible() {
 			return 0xFF
 		}
 
@@ -82,6 +128,9 @@ func (b *MemoryBus) Read(address uint16) uint8 {
 	case address < 0xFF00:
 		// reserved and unusable
 		return 0
+	case address == 0xFF70 && b.ctx.CgbMode:
+		// SVBK: wram bank select
+		return 0xF8 | b.wramBank
 	case address < 0xFF80:
 		// IO registers
 		return b.ctx.Io.Read(address)
@@ -106,25 +155,48 @@ func (b *MemoryBus) Write(address uint16, value uint8) {
 	case address < 0x8000:
 		b.ctx.Cart.Write(address, value)
 	case address < 0xA000:
+		if !b.vramAccessible() {
+			b.logBlockedWrite(address, value)
+			return
+		}
+
 		b.ctx.Ppu.Write(address, value)
 	case address < 0xC000:
 		// cart ram
 		b.ctx.Cart.Write(address, value)
-	case address < 0xE000:
+	case address < 0xD000:
 		// working ram
-		b.wram.Write(address, value)
+		b.wram[0].Write(address, value)
+	case address < 0xE000:
+		// switchable working ram
+		b.wram[b.wramBank].Write(address, value)
 	case address < 0xFE00:
 		// Echo ram is unusable
 	case address < 0xFEA0:
 		if b.ctx.Dma.Active() {
 			return
 		}
+		if !b.oamAccessible() {
+			b.logBlockedWrite(address, value)
+			return
+		}
 		if address == 0xFE40 {
 			log.Printf("w %d,%d", address, value)
 		}
 		b.ctx.Ppu.Write(address, value)
 	case address < 0xFF00:
 		// reserved and unusable
+	case address == 0xFF50:
+		// BANK: writing a non zero value unmaps the boot rom until the next reset
+		if value != 0 {
+			b.bootRomMapped = false
+		}
+	case address == 0xFF70 && b.ctx.CgbMode:
+		// SVBK: wram bank select, bank 0 cannot be selected here and maps to bank 1
+		b.wramBank = value & 0x7
+		if b.wramBank == 0 {
+			b.wramBank = 1
+		}
 	case address < 0xFF80:
 		// IO registers
 		b.ctx.Io.Write(address, value)
@@ -143,3 +215,44 @@ func (b *MemoryBus) Write16(address uint16, value uint16) {
 	b.Write(address, uint8(value&0xFF))
 	b.Write(address+1, uint8(value>>8))
 }
+
+// vramAccessible reports if the cpu can access vram, it is locked while the ppu is drawing
+func (b *MemoryBus) vramAccessible() bool {
+	if !b.ctx.Lcd.GetControl(LcdcLcdPpuEnable) {
+		return true
+	}
+
+	return b.ctx.Lcd.GetMode() != LcdModeDraw
+}
+
+// oamAccessible reports if the cpu can access oam, it is locked during the oam scan and while
+// the ppu is drawing
+func (b *MemoryBus) oamAccessible() bool {
+	if !b.ctx.Lcd.GetControl(LcdcLcdPpuEnable) {
+		return true
+	}
+
+	mode := b.ctx.Lcd.GetMode()
+	return mode != LcdModeOam && mode != LcdModeDraw
+}
+
+// logBlockedWrite reports writes dropped because the ppu had the memory locked, these would
+// silently fail on real hardware so are useful when debugging homebrew
+func (b *MemoryBus) logBlockedWrite(address uint16, value uint8) {
+	if !b.ctx.Debug.Enabled() {
+		return
+	}
+
+	log.Printf("blocked write 0x%02X to 0x%04X in lcd mode %d", value, address, b.ctx.Lcd.GetMode())
+}
+
+// inBootRom reports if the address is covered by the boot rom
+//
+// the cgb boot rom is split in two to leave the cart header at 0x0100 - 0x01FF visible
+func (b *MemoryBus) inBootRom(address uint16) bool {
+	if address < 0x0100 {
+		return true
+	}
+
+	return b.ctx.CgbMode && address >= 0x0200 && address < 0x0900
+}
@@ -0,0 +1,22 @@
+package emu
+
+// Options are the settings passed in on the command line
+type Options struct {
+	// boot roms take priority over the ones set in preferences
+	BootRoms BootRoms
+
+	// link cable addresses in the form network:address, only one should be set
+	LinkListen string
+	LinkDial   string
+
+	// PrinterDir connects a printer to the link port, it takes priority over the one set in preferences
+	PrinterDir string
+
+	// CameraSource is a png or a directory of png frames for the pocket camera to see, it takes
+	// priority over the one set in preferences
+	CameraSource string
+
+	// AudioWav records audio to the given .wav file at AudioRate
+	AudioWav  string
+	AudioRate uint
+}
@@ -27,3 +27,18 @@ func GetColorIdx(hb, lb, bit uint8) int {
 
 	return i
 }
+
+// GetCgbColor converts a little endian RGB555 color from cgb palette ram into a Pixel
+func GetCgbColor(lo, hi uint8) Pixel {
+	rgb := uint16(hi)<<8 | uint16(lo)
+
+	return Pixel{
+		R: scaleCgbChannel(uint8(rgb & 0x1F)),
+		G: scaleCgbChannel(uint8(rgb >> 5 & 0x1F)),
+		B: scaleCgbChannel(uint8(rgb >> 10 & 0x1F)),
+	}
+}
+
+func scaleCgbChannel(c uint8) uint8 {
+	return c<<3 | c>>2
+}
@@ -0,0 +1,165 @@
+package ppu
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/indeedhat/gb-emulator/internal/emu/context"
+	. "github.com/indeedhat/gb-emulator/internal/emu/enum"
+)
+
+// Hdma implements the cgb vram dma engine
+// it runs in one of two modes:
+// - general purpose (GDMA), the whole transfer happens at once
+// - hblank (HDMA), 0x10 bytes are transferred at the start of each hblank
+//
+// the cpu is stalled for the duration of each block being transferred
+type Hdma struct {
+	src uint16
+	dst uint16
+
+	active       bool
+	hblankMode   bool
+	transferring bool
+	remaining    uint8
+	blockBytes   uint8
+
+	ctx *context.Context
+}
+
+func NewHdma(ctx *context.Context) {
+	ctx.Hdma = &Hdma{
+		dst: 0x8000,
+		ctx: ctx,
+	}
+}
+
+func (h *Hdma) LoadState(data []byte) {
+	r := bytes.NewReader(data)
+
+	binary.Read(r, binary.BigEndian, &h.src)
+	binary.Read(r, binary.BigEndian, &h.dst)
+	binary.Read(r, binary.BigEndian, &h.active)
+	binary.Read(r, binary.BigEndian, &h.hblankMode)
+	binary.Read(r, binary.BigEndian, &h.transferring)
+	binary.Read(r, binary.BigEndian, &h.remaining)
+	binary.Read(r, binary.BigEndian, &h.blockBytes)
+}
+
+func (h *Hdma) SaveState() []byte {
+	var buf bytes.Buffer
+
+	binary.Write(&buf, binary.BigEndian, h.src)
+	binary.Write(&buf, binary.BigEndian, h.dst)
+	binary.Write(&buf, binary.BigEndian, h.active)
+	binary.Write(&buf, binary.BigEndian, h.hblankMode)
+	binary.Write(&buf, binary.BigEndian, h.transferring)
+	binary.Write(&buf, binary.BigEndian, h.remaining)
+	binary.Write(&buf, binary.BigEndian, h.blockBytes)
+
+	return buf.Bytes()
+}
+
+func (h *Hdma) Read(address uint16) uint8 {
+	if !h.ctx.CgbMode || address != 0xFF55 {
+		return 0xFF
+	}
+
+	// Bit 7:   0 = Active; 1 = Not active
+	// Bit 6-0: Remaining length (in blocks of 0x10 bytes) - 1
+	value := (h.remaining - 1) & 0x7F
+	if !h.active {
+		value |= 0x80
+	}
+
+	return value
+}
+
+func (h *Hdma) Write(address uint16, value uint8) {
+	if !h.ctx.CgbMode {
+		return
+	}
+
+	switch address {
+	case 0xFF51:
+		h.src = uint16(value)<<8 | h.src&0xF0
+	case 0xFF52:
+		h.src = h.src&0xFF00 | uint16(value&0xF0)
+	case 0xFF53:
+		h.dst = 0x8000 | uint16(value&0x1F)<<8 | h.dst&0xF0
+	case 0xFF54:
+		h.dst = h.dst&0xFF00 | uint16(value&0xF0)
+	case 0xFF55:
+		h.start(value)
+	}
+}
+
+// Transferring reports if a block is currently being copied, the cpu is stalled while this is true
+func (h *Hdma) Transferring() bool {
+	return h.transferring
+}
+
+// Hblank is called by the ppu at the start of each hblank period
+func (h *Hdma) Hblank() {
+	if h.active && h.hblankMode {
+		h.transferring = true
+	}
+}
+
+func (h *Hdma) Tick() {
+	if !h.transferring {
+		return
+	}
+
+	// NB: a block of 0x10 bytes takes 8 cycles in normal speed and 16 in double speed
+	bytesPerCycle := 2
+	if h.ctx.Cpu.DoubleSpeed() {
+		bytesPerCycle = 1
+	}
+
+	for range bytesPerCycle {
+		h.ctx.Ppu.Write(h.dst, h.ctx.Bus.Read(h.src))
+
+		h.src++
+		h.dst = 0x8000 | (h.dst+1)&0x1FFF
+		h.blockBytes++
+
+		if h.blockBytes < 0x10 {
+			continue
+		}
+
+		h.blockBytes = 0
+		h.remaining--
+
+		if h.remaining == 0 {
+			h.active = false
+			h.transferring = false
+			return
+		}
+
+		if h.hblankMode {
+			h.transferring = false
+			return
+		}
+	}
+}
+
+func (h *Hdma) start(value uint8) {
+	// NB: writing with bit 7 cleared while a hblank transfer is running cancels it
+	if h.active && h.hblankMode && value&0x80 == 0 {
+		h.active = false
+		return
+	}
+
+	h.active = true
+	h.hblankMode = value&0x80 == 0x80
+	h.remaining = value&0x7F + 1
+	h.blockBytes = 0
+
+	if !h.hblankMode ||
+		!h.ctx.Lcd.GetControl(LcdcLcdPpuEnable) ||
+		h.ctx.Lcd.GetMode() == LcdModeHblank {
+
+		h.transferring = true
+	}
+}
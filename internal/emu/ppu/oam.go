@@ -27,6 +27,10 @@ type OamEntry struct {
 	flags uint8
 }
 
+func (e OamEntry) CgbPalette() uint8 {
+	return e.flags & 0x7
+}
+
 func (e OamEntry) Check(flag OamFlag) bool {
 	return e.flags&uint8(flag) == uint8(flag)
 }
@@ -59,7 +63,7 @@ func (o *OamRam) Write(address uint16, value uint8) {
 	o.data[address] = value
 }
 
-func (o *OamRam) SelectObjects(y uint8, doubleHight bool) []OamEntry {
+func (o *OamRam) SelectObjects(y uint8, doubleHight, cgb bool) []OamEntry {
 	var (
 		height   uint8 = 8
 		selected       = make([]OamEntry, 0, 10)
@@ -73,10 +77,6 @@ func (o *OamRam) SelectObjects(y uint8, doubleHight bool) []OamEntry {
 			break
 		}
 
-		if entry.x == 0 {
-			continue
-		}
-
 		if entry.y > y+16 || entry.y+height <= y+16 {
 			continue
 		}
@@ -84,6 +84,15 @@ func (o *OamRam) SelectObjects(y uint8, doubleHight bool) []OamEntry {
 		selected = append(selected, entry)
 	}
 
+	// NB: objects with x == 0 are selected so they count towards the 10 object limit but are
+	//     never drawn as they sit entirely off the left of the screen
+	//
+	// NB: in cgb mode object priority is decided purely by oam index
+	if cgb {
+		return selected
+	}
+
+	// NB: on the dmg the object with the lowest x wins, ties are won by the earlier oam index
 	sort.SliceStable(selected, func(i, j int) bool {
 		return selected[i].x < selected[j].x
 	})
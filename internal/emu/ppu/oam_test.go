@@ -0,0 +1,95 @@
+package ppu
+
+import (
+	"slices"
+	"testing"
+)
+
+// newTestOam builds oam from the given entries, each entry's tile index is its oam index so the
+// selection order can be checked, the remaining slots are placed off screen
+func newTestOam(entries ...OamEntry) *OamRam {
+	oam := &OamRam{data: make([]byte, 160)}
+
+	for i, entry := range entries {
+		oam.data[i*4] = entry.y
+		oam.data[i*4+1] = entry.x
+		oam.data[i*4+2] = uint8(i)
+		oam.data[i*4+3] = entry.flags
+	}
+
+	return oam
+}
+
+func selectedIndexes(entries []OamEntry) []uint8 {
+	indexes := make([]uint8, 0, len(entries))
+	for _, entry := range entries {
+		indexes = append(indexes, entry.tileIdx)
+	}
+
+	return indexes
+}
+
+func TestSelectObjects(t *testing.T) {
+	eleven := make([]OamEntry, 11)
+	for i := range eleven {
+		eleven[i] = OamEntry{y: 16, x: 8 + uint8(i)}
+	}
+	eleven[0].x = 0
+
+	unsorted := []OamEntry{
+		{y: 16, x: 30},
+		{y: 16, x: 10},
+		{y: 16, x: 20},
+		{y: 16, x: 10},
+	}
+
+	cases := []struct {
+		name         string
+		entries      []OamEntry
+		ly           uint8
+		doubleHeight bool
+		cgb          bool
+		want         []uint8
+	}{
+		{
+			name:    "x == 0 counts towards the 10 object limit",
+			entries: eleven,
+			want:    []uint8{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		},
+		{
+			name:    "dmg orders by lower x then lower oam index",
+			entries: unsorted,
+			want:    []uint8{1, 3, 2, 0},
+		},
+		{
+			name:    "cgb orders by oam index",
+			entries: unsorted,
+			cgb:     true,
+			want:    []uint8{0, 1, 2, 3},
+		},
+		{
+			name:    "objects not on the line are skipped",
+			entries: []OamEntry{{y: 16, x: 8}, {y: 24, x: 8}},
+			ly:      8,
+			want:    []uint8{1},
+		},
+		{
+			name:         "double height objects cover 16 lines",
+			entries:      []OamEntry{{y: 16, x: 8}, {y: 24, x: 8}},
+			ly:           8,
+			doubleHeight: true,
+			want:         []uint8{0, 1},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			oam := newTestOam(tc.entries...)
+
+			got := selectedIndexes(oam.SelectObjects(tc.ly, tc.doubleHeight, tc.cgb))
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("selected oam indexes %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
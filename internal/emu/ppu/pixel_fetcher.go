@@ -21,6 +21,16 @@ const (
 	PixFetchModePush
 )
 
+// cgb background map attributes, stored in vram bank 1
+type BgAttr uint8
+
+const (
+	BgAttrBank     BgAttr = 1 << 3
+	BgAttrXFlip    BgAttr = 1 << 5
+	BgAttrYFlip    BgAttr = 1 << 6
+	BgAttrPriority BgAttr = 1 << 7
+)
+
 type PixelFetcher struct {
 	mode PixFetchMode
 
@@ -33,12 +43,21 @@ type PixelFetcher struct {
 	mapX    uint8
 	mapY    uint8
 
-	windowX uint8
+	// window state
+	//
+	// windowLine is the window's internal line counter, it only advances on lines where the
+	// window was drawn. windowYTriggered latches once LY has matched WY during the frame so
+	// later changes to WY don't hide the window until the next frame
+	windowLine       uint8
+	windowTileX      uint8
+	windowDiscard    uint8
+	windowYTriggered bool
 
 	frame int
 	done  bool
 
 	bgTileId uint8
+	bgAttr   uint8
 	bgLoBit  uint8
 	bgHiBit  uint8
 
@@ -48,6 +67,16 @@ type PixelFetcher struct {
 
 	pixFifo *PixelFifo
 
+	// mode 3 penalties
+	//
+	// the fetcher is paused for stall dots, starting the window on a line costs 6 dots and
+	// each object 6 dots plus up to 5 more waiting for the background fetch it interrupts
+	// to finish, the wait is only paid once per background tile
+	stall          uint8
+	windowActive   bool
+	chargedSprites uint16
+	alignedTiles   uint32
+
 	ctx *context.Context
 }
 
@@ -70,7 +99,7 @@ func (p *PixelFetcher) LoadState(data []byte) {
 	binary.Read(r, binary.BigEndian, &p.tileY)
 	binary.Read(r, binary.BigEndian, &p.mapX)
 	binary.Read(r, binary.BigEndian, &p.mapY)
-	binary.Read(r, binary.BigEndian, &p.windowX)
+	binary.Read(r, binary.BigEndian, &p.windowLine)
 	binary.Read(r, binary.BigEndian, &p.frame)
 	binary.Read(r, binary.BigEndian, &p.done)
 	binary.Read(r, binary.BigEndian, &p.bgTileId)
@@ -101,6 +130,17 @@ func (p *PixelFetcher) LoadState(data []byte) {
 		binary.Read(r, binary.BigEndian, &p.pixFifo.pixels[i].G)
 		binary.Read(r, binary.BigEndian, &p.pixFifo.pixels[i].B)
 	}
+
+	binary.Read(r, binary.BigEndian, &p.bgAttr)
+
+	binary.Read(r, binary.BigEndian, &p.stall)
+	binary.Read(r, binary.BigEndian, &p.windowActive)
+	binary.Read(r, binary.BigEndian, &p.chargedSprites)
+	binary.Read(r, binary.BigEndian, &p.alignedTiles)
+
+	binary.Read(r, binary.BigEndian, &p.windowTileX)
+	binary.Read(r, binary.BigEndian, &p.windowDiscard)
+	binary.Read(r, binary.BigEndian, &p.windowYTriggered)
 }
 
 func (p *PixelFetcher) SaveState() []byte {
@@ -114,7 +154,7 @@ func (p *PixelFetcher) SaveState() []byte {
 	binary.Write(&buf, binary.BigEndian, p.tileY)
 	binary.Write(&buf, binary.BigEndian, p.mapX)
 	binary.Write(&buf, binary.BigEndian, p.mapY)
-	binary.Write(&buf, binary.BigEndian, p.windowX)
+	binary.Write(&buf, binary.BigEndian, p.windowLine)
 	binary.Write(&buf, binary.BigEndian, p.frame)
 	binary.Write(&buf, binary.BigEndian, p.done)
 	binary.Write(&buf, binary.BigEndian, p.bgTileId)
@@ -142,6 +182,17 @@ func (p *PixelFetcher) SaveState() []byte {
 		binary.Write(&buf, binary.BigEndian, p.pixFifo.pixels[i].B)
 	}
 
+	binary.Write(&buf, binary.BigEndian, p.bgAttr)
+
+	binary.Write(&buf, binary.BigEndian, p.stall)
+	binary.Write(&buf, binary.BigEndian, p.windowActive)
+	binary.Write(&buf, binary.BigEndian, p.chargedSprites)
+	binary.Write(&buf, binary.BigEndian, p.alignedTiles)
+
+	binary.Write(&buf, binary.BigEndian, p.windowTileX)
+	binary.Write(&buf, binary.BigEndian, p.windowDiscard)
+	binary.Write(&buf, binary.BigEndian, p.windowYTriggered)
+
 	return buf.Bytes()
 }
 
@@ -152,13 +203,49 @@ func (p *PixelFetcher) Reset() {
 	p.lineX = 0
 	p.fifoX = 0
 	p.done = false
+
+	p.stall = 0
+	p.windowActive = false
+	p.chargedSprites = 0
+	p.alignedTiles = 0
+
+	p.windowTileX = 0
+	p.windowDiscard = 0
+	if p.ctx.Lcd.Ly() == p.ctx.Lcd.WindowY() {
+		p.windowYTriggered = true
+	}
+}
+
+// EndLine is called at the end of mode 3
+func (p *PixelFetcher) EndLine() {
+	if p.windowActive {
+		p.windowLine++
+	}
 }
 
+// ResetWindow is called at the start of each frame
+func (p *PixelFetcher) ResetWindow() {
+	p.windowLine = 0
+	p.windowYTriggered = false
+}
+
+// Process runs the fetcher for a single dot
+//
+// NB: the SCX fine scroll penalty comes from the scx % 8 pixels discarded by pushPixel
 func (p *PixelFetcher) Process() {
+	if p.stall > 0 {
+		p.stall--
+		return
+	}
+
 	p.mapX = p.fetched + p.ctx.Lcd.ScrollX()
 	p.mapY = p.ctx.Lcd.Ly() + p.ctx.Lcd.ScrollY()
 	p.tileY = (p.mapY % 8) * 2
 
+	if p.windowActive {
+		p.tileY = (p.windowLine % 8) * 2
+	}
+
 	if p.ctx.Ppu.(*Ppu).ticks%2 == 0 {
 		p.fetch()
 	}
@@ -173,15 +260,17 @@ func (p *PixelFetcher) fetch() {
 
 	case PixFetchModeDataHigh:
 		p.mode = PixFetchModeDataLow
-		p.bgHiBit = p.ctx.Bus.Read(
-			p.ctx.Lcd.BgWinTileAddress(uint16(p.bgTileId)*16 + uint16(p.tileY) + 1),
+		p.bgHiBit = p.readVram(
+			p.bgTileBank(),
+			p.ctx.Lcd.BgWinTileAddress(uint16(p.bgTileId)*16+uint16(p.bgTileRow())+1),
 		)
 		p.loadSpriteTileData(true)
 
 	case PixFetchModeDataLow:
 		p.mode = PixFetchModeSleep
-		p.bgLoBit = p.ctx.Bus.Read(
-			p.ctx.Lcd.BgWinTileAddress(uint16(p.bgTileId)*16 + uint16(p.tileY)),
+		p.bgLoBit = p.readVram(
+			p.bgTileBank(),
+			p.ctx.Lcd.BgWinTileAddress(uint16(p.bgTileId)*16+uint16(p.bgTileRow())),
 		)
 		p.loadSpriteTileData(false)
 
@@ -197,19 +286,16 @@ func (p *PixelFetcher) fetch() {
 func (p *PixelFetcher) doFetchModeTile() {
 	p.fetchedOam = nil
 
-	if p.ctx.Lcd.GetControl(LcdcBgwEnable) {
-		if p.WindowVisible() &&
-			(p.fetched+7 >= p.ctx.Lcd.WindowX() && p.fetched+7 < p.ctx.Lcd.WindowX()+config.PpuYRes+14) &&
-			(p.ctx.Lcd.Ly() >= p.ctx.Lcd.WindowY() && p.ctx.Lcd.Ly() < p.ctx.Lcd.WindowY()+config.PpuXRes) {
+	// NB: in cgb mode LCDC bit 0 only controls bg priority, the background is always drawn
+	if p.ctx.Lcd.GetControl(LcdcBgwEnable) || p.ctx.CgbMode {
+		mapAddress := p.ctx.Lcd.BgTileAddress(uint16(p.mapX/8) + uint16(p.mapY/8)*32)
+		if p.windowActive {
+			mapAddress = p.ctx.Lcd.WinTileAddress(uint16(p.windowTileX) + uint16(p.windowLine/8)*32)
+		}
 
-			p.bgTileId = p.ctx.Bus.Read(p.ctx.Lcd.WinTileAddress(
-				(uint16(p.fetched+7-p.ctx.Lcd.WindowX()) / 8) +
-					(uint16(p.windowX)/8)*32,
-			))
-		} else {
-			p.bgTileId = p.ctx.Bus.Read(
-				p.ctx.Lcd.BgTileAddress(uint16(p.mapX/8) + uint16(p.mapY/8)*32),
-			)
+		p.bgTileId = p.readVram(0, mapAddress)
+		if p.ctx.CgbMode {
+			p.bgAttr = p.readVram(1, mapAddress)
 		}
 
 		if !p.ctx.Lcd.GetControl(LcdcBgwTileArea) {
@@ -218,21 +304,22 @@ func (p *PixelFetcher) doFetchModeTile() {
 	}
 
 	if p.ctx.Lcd.GetControl(LcdcObjecteEnable) && len(p.ctx.Ppu.(*Ppu).activeSprites) > 0 {
-		for _, entry := range p.ctx.Ppu.(*Ppu).activeSprites {
+		for i, entry := range p.ctx.Ppu.(*Ppu).activeSprites {
 			x := (entry.x - 8) + p.ctx.Lcd.ScrollX()%8
 
 			if (x >= p.fetched && x < p.fetched+8) ||
 				(x+8 >= p.fetched && x+8 < p.fetched+8) {
 
 				p.fetchedOam = append(p.fetchedOam, entry)
-			}
-
-			if len(p.fetchedOam) == 3 {
-				break
+				p.chargeSprite(i, entry)
 			}
 		}
 	}
 
+	if p.windowActive {
+		p.windowTileX = (p.windowTileX + 1) % 32
+	}
+
 	p.fetched += 8
 	p.mode = PixFetchModeDataHigh
 
@@ -240,6 +327,26 @@ func (p *PixelFetcher) doFetchModeTile() {
 	p.spriteLoBit = make([]uint8, len(p.fetchedOam))
 }
 
+// chargeSprite stalls the fetcher for the time taken to fetch an object, each object is only
+// charged once per line even though it can be picked up by two tile fetches
+func (p *PixelFetcher) chargeSprite(i int, entry OamEntry) {
+	if p.chargedSprites&(1<<i) != 0 {
+		return
+	}
+
+	p.chargedSprites |= 1 << i
+	p.stall += 6
+
+	pos := entry.x + p.ctx.Lcd.ScrollX()%8
+	tile := pos / 8
+	if p.alignedTiles&(1<<tile) != 0 {
+		return
+	}
+
+	p.alignedTiles |= 1 << tile
+	p.stall += 5 - min(5, pos%8)
+}
+
 func (p *PixelFetcher) loadSpriteTileData(hi bool) {
 	var height uint8 = 8
 	if p.ctx.Lcd.GetControl(LcdcObjecteDoubleHeight) {
@@ -257,10 +364,15 @@ func (p *PixelFetcher) loadSpriteTileData(hi bool) {
 			tileId &= 0xFE
 		}
 
+		var bank uint8
+		if p.ctx.CgbMode && entry.Check(OamFlagBank) {
+			bank = 1
+		}
+
 		if hi {
-			p.spriteHiBit[i] = p.ctx.Bus.Read(0x8000 + uint16(tileId)*16 + uint16(y) + 1)
+			p.spriteHiBit[i] = p.readVram(bank, 0x8000+uint16(tileId)*16+uint16(y)+1)
 		} else {
-			p.spriteLoBit[i] = p.ctx.Bus.Read(0x8000 + uint16(tileId)*16 + uint16(y))
+			p.spriteLoBit[i] = p.readVram(bank, 0x8000+uint16(tileId)*16+uint16(y))
 		}
 	}
 }
@@ -274,11 +386,22 @@ func (p *PixelFetcher) fetchPixels() {
 	xPos := p.fetched - (8 - (p.ctx.Lcd.ScrollX() % 8))
 
 	for i := 7; i >= 0; i-- {
-		cid := palette.GetColorIdx(p.bgHiBit, p.bgLoBit, uint8(i))
-		c := palette.GetColor(p.ctx.Lcd.BackgroundPallet(), p.bgHiBit, p.bgLoBit, uint8(i))
+		bit := uint8(i)
+		if p.checkBgAttr(BgAttrXFlip) {
+			bit = uint8(7 - i)
+		}
 
-		if !p.ctx.Lcd.GetControl(LcdcBgwEnable) {
+		cid := palette.GetColorIdx(p.bgHiBit, p.bgLoBit, bit)
+
+		var c Pixel
+		if p.ctx.CgbMode {
+			c = p.ctx.Lcd.CgbBackgroundColor(p.bgAttr&0x7, uint8(cid))
+		} else if p.ctx.Lcd.GetControl(LcdcBgwEnable) {
+			c = palette.GetColor(p.ctx.Lcd.BackgroundPallet(), p.bgHiBit, p.bgLoBit, bit)
+		} else {
+			// NB: with the dmg background disabled it is drawn as colour 0 so never covers objects
 			c = palette.ColorPallet[p.ctx.Lcd.BackgroundPallet()&0b11]
+			cid = 0
 		}
 
 		if p.ctx.Lcd.GetControl(LcdcObjecteEnable) {
@@ -287,6 +410,12 @@ func (p *PixelFetcher) fetchPixels() {
 			}
 		}
 
+		// NB: with WX < 7 the window starts off the left of the screen
+		if p.windowDiscard > 0 {
+			p.windowDiscard--
+			continue
+		}
+
 		if xPos >= 0 {
 			p.pixFifo.Enqueue(c)
 			p.fifoX++
@@ -294,8 +423,17 @@ func (p *PixelFetcher) fetchPixels() {
 	}
 }
 
+// fetchSpritePixel finds the object pixel to draw over the background at the current position
+//
+// fetchedOam is in priority order so the first object with a non transparent pixel wins, if
+// the background has priority over that object the background is drawn instead, objects
+// further down the list never show through
 func (p *PixelFetcher) fetchSpritePixel(bgColorId int) *Pixel {
 	for i, entry := range p.fetchedOam {
+		if entry.x == 0 {
+			continue
+		}
+
 		x := (entry.x - 8) + p.ctx.Lcd.ScrollX()%8
 		if x+8 < p.fifoX {
 			continue
@@ -318,8 +456,13 @@ func (p *PixelFetcher) fetchSpritePixel(bgColorId int) *Pixel {
 			continue
 		}
 
-		if entry.Check(OamFlagPriority) && bgColorId != 0 {
-			continue
+		if bgColorId != 0 && p.bgHasPriority(entry) {
+			return nil
+		}
+
+		if p.ctx.CgbMode {
+			pix := p.ctx.Lcd.CgbObjectColor(entry.CgbPalette(), uint8(palette.GetColorIdx(hiBit, loBit, bit)))
+			return &pix
 		}
 
 		activePalette := p.ctx.Lcd.ObjectPallet(0)
@@ -334,7 +477,49 @@ func (p *PixelFetcher) fetchSpritePixel(bgColorId int) *Pixel {
 	return nil
 }
 
+// bgHasPriority reports if a non zero background pixel should be drawn over the given object
+func (p *PixelFetcher) bgHasPriority(entry OamEntry) bool {
+	if !p.ctx.CgbMode {
+		return entry.Check(OamFlagPriority)
+	}
+
+	// NB: in cgb mode clearing LCDC bit 0 gives objects priority over everything
+	if !p.ctx.Lcd.GetControl(LcdcBgwEnable) {
+		return false
+	}
+
+	return p.checkBgAttr(BgAttrPriority) || entry.Check(OamFlagPriority)
+}
+
+func (p *PixelFetcher) checkBgAttr(attr BgAttr) bool {
+	return p.bgAttr&uint8(attr) == uint8(attr)
+}
+
+func (p *PixelFetcher) bgTileBank() uint8 {
+	if p.checkBgAttr(BgAttrBank) {
+		return 1
+	}
+
+	return 0
+}
+
+func (p *PixelFetcher) bgTileRow() uint8 {
+	if p.checkBgAttr(BgAttrYFlip) {
+		return 14 - p.tileY
+	}
+
+	return p.tileY
+}
+
+func (p *PixelFetcher) readVram(bank uint8, address uint16) uint8 {
+	return p.ctx.Ppu.(*Ppu).ReadVram(bank, address)
+}
+
 func (p *PixelFetcher) pushPixel() {
+	if p.startWindow() {
+		return
+	}
+
 	if p.pixFifo.fill <= 8 {
 		return
 	}
@@ -354,17 +539,45 @@ func (p *PixelFetcher) pushPixel() {
 	p.lineX++
 }
 
+// WindowVisible reports if the window can be drawn on the current line
 func (p *PixelFetcher) WindowVisible() bool {
-	if !p.ctx.Lcd.GetControl(LcdcWindowEnable) {
+	return p.ctx.Lcd.GetControl(LcdcWindowEnable) &&
+		p.ctx.Lcd.WindowX() <= 166 &&
+		p.windowYTriggered
+}
+
+// startWindow switches the fetcher over to the window when the next pixel to be pushed is at
+// WX - 7, the fifo is cleared and fetching restarts from the first window tile
+//
+// the window starts at screen x 0 for WX 0 - 6 with the first 7 - WX pixels cut off, WX 166
+// only shows a single column
+func (p *PixelFetcher) startWindow() bool {
+	if p.windowActive || !p.WindowVisible() {
 		return false
 	}
 
-	return p.ctx.Lcd.WindowX() >= 0 && p.ctx.Lcd.WindowX() <= 166 &&
-		p.ctx.Lcd.WindowY() >= 0 && p.ctx.Lcd.WindowY() < config.PpuYRes
-}
+	fineScroll := p.ctx.Lcd.ScrollX() % 8
+	if p.lineX < fineScroll {
+		return false
+	}
+
+	screenX := int(p.lineX - fineScroll)
+	start := int(p.ctx.Lcd.WindowX()) - 7
+	if screenX != start && !(screenX == 0 && start < 0) {
+		return false
+	}
+
+	p.windowActive = true
+	p.windowTileX = 0
+	p.windowDiscard = uint8(max(0, -start))
 
-func (p *PixelFetcher) IncrementWindowX() {
-	p.windowX++
+	p.pixFifo.Reset()
+	p.fifoX = p.lineX
+	p.fetched = p.lineX
+	p.mode = PixFetchModeTile
+	p.stall += 6
+
+	return true
 }
 
 type PixelFifo struct {
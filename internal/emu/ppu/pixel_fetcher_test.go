@@ -0,0 +1,213 @@
+package ppu
+
+import (
+	"testing"
+
+	"github.com/indeedhat/gb-emulator/internal/emu/config"
+	"github.com/indeedhat/gb-emulator/internal/emu/context"
+	. "github.com/indeedhat/gb-emulator/internal/emu/enum"
+	"github.com/indeedhat/gb-emulator/internal/emu/lcd"
+	"github.com/indeedhat/gb-emulator/internal/emu/palette"
+	. "github.com/indeedhat/gb-emulator/internal/emu/types"
+)
+
+// stubCpu ignores the interrupts requested by the ppu
+type stubCpu struct{}
+
+func (stubCpu) RequestInterrupt(itype uint8)     {}
+func (stubCpu) Step() error                      { return nil }
+func (stubCpu) InterruptFlags() uint8            { return 0 }
+func (stubCpu) SetInterruptFlags(value uint8)    {}
+func (stubCpu) InterruptRegister() uint8         { return 0 }
+func (stubCpu) SetInterruptRegister(value uint8) {}
+func (stubCpu) DoubleSpeed() bool                { return false }
+func (stubCpu) Key1() uint8                      { return 0xFF }
+func (stubCpu) SetKey1(value uint8)              {}
+
+// stubHdma never has anything to copy
+type stubHdma struct{}
+
+func (stubHdma) Read(address uint16) uint8         { return 0xFF }
+func (stubHdma) Write(address uint16, value uint8) {}
+func (stubHdma) Tick()                             {}
+func (stubHdma) Transferring() bool                { return false }
+func (stubHdma) Hblank()                           {}
+
+// window test tiles, the background is drawn entirely with tile 0
+const (
+	tileBlank     = 0
+	tileLeftEdge  = 1
+	tileSolid     = 2
+	lcdcWithWin   = 0xF1
+	lcdcWithoutWn = 0xD1
+)
+
+var (
+	white = palette.ColorPallet[0]
+	black = palette.ColorPallet[3]
+)
+
+// newWindowTestPpu creates a dmg ppu with a white background and the window map filled with tile
+//
+// tileLeftEdge only has its first column set so the start of each window tile can be found,
+// tileSolid is black throughout
+func newWindowTestPpu(tile uint8, wx, wy uint8) *Ppu {
+	ctx := context.NewContext()
+	ctx.FrameCh = make(chan []Pixel, 4)
+	ctx.Cpu = stubCpu{}
+	ctx.Hdma = stubHdma{}
+
+	lcd.New(ctx)
+	New(ctx)
+	NewPixelFetcher(ctx)
+
+	p := ctx.Ppu.(*Ppu)
+
+	for row := range uint16(8) {
+		p.Write(0x8000+uint16(tileLeftEdge)*16+row*2, 0x80)
+		p.Write(0x8000+uint16(tileLeftEdge)*16+row*2+1, 0x80)
+		p.Write(0x8000+uint16(tileSolid)*16+row*2, 0xFF)
+		p.Write(0x8000+uint16(tileSolid)*16+row*2+1, 0xFF)
+	}
+
+	for i := range uint16(0x400) {
+		p.Write(0x9800+i, tileBlank)
+		p.Write(0x9C00+i, tile)
+	}
+
+	ctx.Lcd.Write(0xFF40, lcdcWithWin)
+	ctx.Lcd.Write(0xFF4A, wy)
+	ctx.Lcd.Write(0xFF4B, wx)
+
+	return p
+}
+
+// startLine ticks the ppu until the oam scan for the given line begins
+func startLine(p *Ppu, ly uint8) {
+	for p.ctx.Lcd.Ly() != ly || p.ctx.Lcd.GetMode() != LcdModeOam {
+		p.Tick()
+	}
+}
+
+// finishLine ticks the ppu until the current line has been drawn
+func finishLine(p *Ppu) {
+	for p.ctx.Lcd.GetMode() != LcdModeHblank {
+		p.Tick()
+	}
+}
+
+func pixelAt(p *Ppu, x, y int) Pixel {
+	return p.nextFrame[y*config.PpuXRes+x]
+}
+
+func TestWindowLineCounter(t *testing.T) {
+	cases := []struct {
+		name     string
+		disabled [2]uint8
+		want     uint8
+	}{
+		{name: "advances on every line the window is drawn", want: 30},
+		{name: "holds while the window is disabled", disabled: [2]uint8{10, 20}, want: 20},
+		{name: "holds while the window is disabled at the top of the frame", disabled: [2]uint8{0, 5}, want: 25},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := newWindowTestPpu(tileSolid, 7, 0)
+			pix := p.ctx.Pix.(*PixelFetcher)
+
+			for ly := range uint8(30) {
+				startLine(p, ly)
+
+				lcdc := uint8(lcdcWithWin)
+				if ly >= tc.disabled[0] && ly < tc.disabled[1] {
+					lcdc = lcdcWithoutWn
+				}
+				p.ctx.Lcd.Write(0xFF40, lcdc)
+
+				finishLine(p)
+			}
+
+			if pix.windowLine != tc.want {
+				t.Fatalf("windowLine = %d, want %d", pix.windowLine, tc.want)
+			}
+		})
+	}
+}
+
+func TestWindowX(t *testing.T) {
+	cases := []struct {
+		name string
+		tile uint8
+		wx   uint8
+		// x of the first black pixel on the line, -1 if there should be none
+		wantFirst int
+		wantCount int
+	}{
+		{name: "WX 7 starts at the left edge", tile: tileLeftEdge, wx: 7, wantFirst: 0, wantCount: 20},
+		{name: "WX 6 discards 1 pixel", tile: tileLeftEdge, wx: 6, wantFirst: 7, wantCount: 20},
+		{name: "WX 3 discards 4 pixels", tile: tileLeftEdge, wx: 3, wantFirst: 4, wantCount: 20},
+		{name: "WX 0 discards 7 pixels", tile: tileLeftEdge, wx: 0, wantFirst: 1, wantCount: 20},
+		{name: "WX 87 starts mid line", tile: tileLeftEdge, wx: 87, wantFirst: 80, wantCount: 10},
+		{name: "WX 166 draws a single column", tile: tileSolid, wx: 166, wantFirst: 159, wantCount: 1},
+		{name: "WX 167 is off screen", tile: tileSolid, wx: 167, wantFirst: -1, wantCount: 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := newWindowTestPpu(tc.tile, tc.wx, 0)
+			startLine(p, 0)
+			finishLine(p)
+
+			first, count := -1, 0
+			for x := range config.PpuXRes {
+				switch pixelAt(p, x, 0) {
+				case black:
+					if first == -1 {
+						first = x
+					}
+					count++
+				case white:
+				default:
+					t.Fatalf("pixel %d was not drawn", x)
+				}
+			}
+
+			if first != tc.wantFirst {
+				t.Fatalf("first window pixel at x %d, want %d", first, tc.wantFirst)
+			}
+			if count != tc.wantCount {
+				t.Fatalf("%d window pixels drawn, want %d", count, tc.wantCount)
+			}
+		})
+	}
+}
+
+func TestWindowYTrigger(t *testing.T) {
+	cases := []struct {
+		name        string
+		wy          uint8
+		newWy       uint8
+		wantVisible bool
+	}{
+		{name: "moving WY below LY mid frame does not hide the window", wy: 0, newWy: 100, wantVisible: true},
+		{name: "moving WY above LY mid frame does not show the window", wy: 100, newWy: 5, wantVisible: false},
+		{name: "WY is compared against LY each line", wy: 100, newWy: 15, wantVisible: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := newWindowTestPpu(tileSolid, 7, tc.wy)
+
+			startLine(p, 10)
+			p.ctx.Lcd.Write(0xFF4A, tc.newWy)
+
+			startLine(p, 20)
+			finishLine(p)
+
+			if visible := pixelAt(p, 0, 20) == black; visible != tc.wantVisible {
+				t.Fatalf("window visible on line 20 = %t, want %t", visible, tc.wantVisible)
+			}
+		})
+	}
+}
@@ -9,38 +9,58 @@ import (
 	"github.com/indeedhat/gb-emulator/internal/emu/config"
 	"github.com/indeedhat/gb-emulator/internal/emu/context"
 	. "github.com/indeedhat/gb-emulator/internal/emu/enum"
+	"github.com/indeedhat/gb-emulator/internal/emu/palette"
 	. "github.com/indeedhat/gb-emulator/internal/emu/types"
 )
 
 type Ppu struct {
-	oam  *OamRam
-	vram *RamBank
+	oam      *OamRam
+	vram     []*RamBank
+	vramBank uint8
 
 	prevFrameTime time.Time
 	ticks         uint64
 	nextFrame     []Pixel
 	blankFrame    []Pixel
+	whiteFrame    []Pixel
 	currentFrame  []Pixel
 	cfMux         sync.Mutex
 	windowX       uint
 
 	activeSprites []OamEntry
 
+	// lcd enable state, when the lcd is turned back on the first line skips the oam scan and
+	// the first frame is not displayed
+	lcdOff      bool
+	lcdStarting bool
+	skipFrame   bool
+
 	ctx *context.Context
 }
 
 func New(ctx *context.Context) {
 	ppu := &Ppu{
 		oam:          &OamRam{make([]byte, 160)},
-		vram:         NewRamBank(0x8000, 0x2000),
+		vram:         []*RamBank{NewRamBank(0x8000, 0x2000)},
 		nextFrame:    make([]Pixel, config.PpuYRes*config.PpuXRes),
 		currentFrame: make([]Pixel, config.PpuYRes*config.PpuXRes),
 		blankFrame:   make([]Pixel, config.PpuYRes*config.PpuXRes),
+		whiteFrame:   make([]Pixel, config.PpuYRes*config.PpuXRes),
 		ctx:          ctx,
 	}
 
+	white := palette.ColorPallet[0]
+	if ctx.CgbMode {
+		white = Pixel{R: 0xFF, G: 0xFF, B: 0xFF}
+	}
+
 	for i := range config.PpuXRes * config.PpuYRes {
 		ppu.blankFrame[i] = Pixel{R: 0xFF, G: 0x00, B: 0xFF}
+		ppu.whiteFrame[i] = white
+	}
+
+	if ctx.CgbMode {
+		ppu.vram = append(ppu.vram, NewRamBank(0x8000, 0x2000))
 	}
 
 	ctx.Ppu = ppu
@@ -53,29 +73,57 @@ func (p *Ppu) LoadState(data []byte) {
 	r.Read(o)
 	p.oam.Fill(o)
 
-	v := p.vram.Bytes()
+	v := p.vram[0].Bytes()
 	r.Read(v)
-	p.vram.Fill(v)
+	p.vram[0].Fill(v)
 
 	binary.Read(r, binary.BigEndian, &p.ticks)
 	binary.Read(r, binary.BigEndian, &p.windowX)
+
+	for _, bank := range p.vram[1:] {
+		v := bank.Bytes()
+		r.Read(v)
+		bank.Fill(v)
+	}
+	binary.Read(r, binary.BigEndian, &p.vramBank)
+
+	binary.Read(r, binary.BigEndian, &p.lcdOff)
+	binary.Read(r, binary.BigEndian, &p.lcdStarting)
+	binary.Read(r, binary.BigEndian, &p.skipFrame)
 }
 
 func (p *Ppu) SaveState() []byte {
 	var buf bytes.Buffer
 
 	buf.Write(p.oam.Bytes())
-	buf.Write(p.vram.Bytes())
+	buf.Write(p.vram[0].Bytes())
 
 	binary.Write(&buf, binary.BigEndian, p.ticks)
 	binary.Write(&buf, binary.BigEndian, p.windowX)
 
+	for _, bank := range p.vram[1:] {
+		buf.Write(bank.Bytes())
+	}
+	binary.Write(&buf, binary.BigEndian, p.vramBank)
+
+	binary.Write(&buf, binary.BigEndian, p.lcdOff)
+	binary.Write(&buf, binary.BigEndian, p.lcdStarting)
+	binary.Write(&buf, binary.BigEndian, p.skipFrame)
+
 	return buf.Bytes()
 }
 
 func (p *Ppu) Read(address uint16) uint8 {
+	if address == 0xFF4F {
+		if !p.ctx.CgbMode {
+			return 0xFF
+		}
+
+		return 0xFE | p.vramBank
+	}
+
 	if address < 0xA000 {
-		return p.vram.Read(address)
+		return p.vram[p.vramBank].Read(address)
 	}
 
 	if address < 0xFEA0 {
@@ -86,14 +134,38 @@ func (p *Ppu) Read(address uint16) uint8 {
 }
 
 func (p *Ppu) Write(address uint16, value uint8) {
-	if address < 0xA000 {
-		p.vram.Write(address, value)
+	if address == 0xFF4F {
+		if p.ctx.CgbMode {
+			p.vramBank = value & 0x1
+		}
+	} else if address < 0xA000 {
+		p.vram[p.vramBank].Write(address, value)
 	} else if address < 0xFEA0 {
 		p.oam.Write(address, value)
 	}
 }
 
+// ReadVram reads directly from the given vram bank regardless of the bank selected by VBK
+func (p *Ppu) ReadVram(bank uint8, address uint16) uint8 {
+	return p.vram[bank].Read(address)
+}
+
 func (p *Ppu) Tick() {
+	if !p.ctx.Lcd.GetControl(LcdcLcdPpuEnable) {
+		p.tickDisabled()
+		return
+	}
+
+	if p.lcdOff {
+		p.lcdOff = false
+		p.lcdStarting = true
+		p.skipFrame = true
+		p.ctx.Pix.(*PixelFetcher).ResetWindow()
+
+		// NB: the first line after turning the lcd on is 4 dots short
+		p.ticks = 4
+	}
+
 	p.ticks++
 
 	switch p.ctx.Lcd.GetMode() {
@@ -108,7 +180,53 @@ func (p *Ppu) Tick() {
 	}
 }
 
+// tickDisabled keeps time while the lcd is off, a white frame is sent at the normal frame rate
+// so the display is blanked and emulation stays paced
+func (p *Ppu) tickDisabled() {
+	if !p.lcdOff {
+		p.lcdOff = true
+		p.lcdStarting = false
+		p.ticks = 0
+		p.sendFrame(p.whiteFrame)
+	}
+
+	p.ticks++
+	if p.ticks < config.PpuTicksPerLine*config.PpuLinesPerFrame {
+		return
+	}
+
+	p.ticks = 0
+	p.sendFrame(p.whiteFrame)
+	p.awaitNextFrame()
+}
+
+func (p *Ppu) sendFrame(pixels []Pixel) {
+	p.cfMux.Lock()
+	defer p.cfMux.Unlock()
+
+	copy(p.currentFrame, pixels)
+
+	// send to renderer
+	frame := make([]Pixel, len(p.currentFrame))
+	copy(frame, p.currentFrame)
+	p.ctx.FrameCh <- frame
+}
+
 func (p *Ppu) doHblank() {
+	// NB: there is no oam scan on the first line after the lcd is turned on, the ppu reports
+	//     mode 0 in its place and draws the line without objects
+	if p.lcdStarting {
+		if p.ticks < 80 {
+			return
+		}
+
+		p.lcdStarting = false
+		p.activeSprites = nil
+		p.ctx.Lcd.SetMode(LcdModeDraw)
+		p.ctx.Pix.(*PixelFetcher).Reset()
+		return
+	}
+
 	if p.ticks < config.PpuTicksPerLine {
 		return
 	}
@@ -122,11 +240,7 @@ func (p *Ppu) doHblank() {
 	}
 
 	p.ctx.Lcd.SetMode(LcdModeVblank)
-
 	p.ctx.Cpu.RequestInterrupt(InterruptVBlank)
-	if p.ctx.Lcd.GetStatus(LcdStatusVblank) {
-		p.ctx.Cpu.RequestInterrupt(InterruptLcdStat)
-	}
 }
 
 func (p *Ppu) awaitNextFrame() {
@@ -139,34 +253,39 @@ func (p *Ppu) awaitNextFrame() {
 }
 
 func (p *Ppu) doVblank() {
+	// NB: LY only reads 153 for the first M-cycle of the last line, it then reads 0 (and is
+	//     compared against LYC as 0) for the rest of the line
+	if p.ticks == 4 && p.ctx.Lcd.Ly() == config.PpuLinesPerFrame-1 {
+		p.ctx.Lcd.ResetLy()
+	}
+
 	if p.ticks < config.PpuTicksPerLine {
 		return
 	}
 
-	p.ctx.Lcd.IncrementLine()
-	if p.ctx.Lcd.Ly() >= config.PpuLinesPerFrame {
-		p.ctx.Lcd.SetMode(LcdModeOam)
-		p.ctx.Lcd.ResetLy()
-		p.ctx.Pix.(*PixelFetcher).windowX = 0
+	p.ticks = 0
 
-		if !p.ctx.Pix.(*PixelFetcher).done {
-			p.cfMux.Lock()
-			copy(p.currentFrame, p.nextFrame)
+	if p.ctx.Lcd.Ly() != 0 {
+		p.ctx.Lcd.IncrementLine()
+		return
+	}
 
-			// send to renderer
-			frame := make([]Pixel, len(p.currentFrame))
-			copy(frame, p.currentFrame)
-			p.ctx.FrameCh <- frame
-			p.cfMux.Unlock()
+	p.ctx.Lcd.SetMode(LcdModeOam)
+	p.ctx.Pix.(*PixelFetcher).ResetWindow()
 
-			copy(p.nextFrame, p.blankFrame)
-			p.ctx.Pix.(*PixelFetcher).done = true
+	if !p.ctx.Pix.(*PixelFetcher).done {
+		if p.skipFrame {
+			p.skipFrame = false
+			p.sendFrame(p.whiteFrame)
+		} else {
+			p.sendFrame(p.nextFrame)
 		}
 
-		p.awaitNextFrame()
+		copy(p.nextFrame, p.blankFrame)
+		p.ctx.Pix.(*PixelFetcher).done = true
 	}
 
-	p.ticks = 0
+	p.awaitNextFrame()
 }
 
 func (p *Ppu) doOam() {
@@ -174,6 +293,7 @@ func (p *Ppu) doOam() {
 		p.activeSprites = p.oam.SelectObjects(
 			p.ctx.Lcd.Ly(),
 			p.ctx.Lcd.GetControl(LcdcObjecteDoubleHeight),
+			p.ctx.CgbMode,
 		)
 	}
 	if p.ticks < 80 {
@@ -192,10 +312,8 @@ func (p *Ppu) doDraw() {
 	}
 
 	p.ctx.Pix.(*PixelFetcher).pixFifo.Reset()
+	p.ctx.Pix.(*PixelFetcher).EndLine()
 
 	p.ctx.Lcd.SetMode(LcdModeHblank)
-
-	if p.ctx.Lcd.GetStatus(LcdStatusHblank) {
-		p.ctx.Cpu.RequestInterrupt(InterruptLcdStat)
-	}
+	p.ctx.Hdma.Hblank()
 }
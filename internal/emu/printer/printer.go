@@ -0,0 +1,263 @@
+package printer
+
+import (
+	"log"
+
+	. "github.com/indeedhat/gb-emulator/internal/emu/types"
+)
+
+// packet commands
+const (
+	CommandInit   = 0x01
+	CommandPrint  = 0x02
+	CommandData   = 0x04
+	CommandStatus = 0x0F
+)
+
+// status flags
+const (
+	StatusChecksumError = uint8(1)
+	StatusPrinting      = uint8(1) << 1
+	StatusImageFull     = uint8(1) << 2
+	StatusUnprocessed   = uint8(1) << 3
+)
+
+const (
+	// reply to the first byte after the checksum to let the game know a printer is connected
+	printerAlive = 0x81
+
+	// the printer holds at most 9 packets of 0x280 bytes of tile data (160x144 pixels)
+	maxImageSize = 0x280 * 9
+
+	// number of status requests the printer reports as busy for after printing
+	printStatusPolls = 4
+)
+
+// packet parse states
+const (
+	stateMagic1 = iota
+	stateMagic2
+	stateCommand
+	stateCompression
+	stateLengthLo
+	stateLengthHi
+	stateData
+	stateChecksumLo
+	stateChecksumHi
+	stateAlive
+	stateStatus
+)
+
+// Printer emulates the game boy printer, it is plugged into the serial port in place of a link cable
+//
+// each packet is made up of:
+// - 2 magic bytes 0x88 0x33
+// - command
+// - compression flag
+// - 16 bit little endian data length
+// - data
+// - 16 bit little endian checksum of everything from the command to the end of the data
+// - 2 bytes of 0x00, the printer replies 0x81 to the first and its status to the second
+//
+// each print command renders the buffered image to a png in the output directory
+type Printer struct {
+	outputDir string
+
+	state       uint8
+	command     uint8
+	compressed  bool
+	length      uint16
+	data        []byte
+	checksum    uint16
+	sum         uint16
+	status      uint8
+	printPolls  uint8
+	image       []byte
+	printNumber int
+}
+
+func New(outputDir string) *Printer {
+	return &Printer{
+		outputDir: outputDir,
+	}
+}
+
+// Transfer implements LinkCable.
+func (p *Printer) Transfer(value uint8) uint8 {
+	switch p.state {
+	case stateMagic1:
+		if value == 0x88 {
+			p.state = stateMagic2
+		}
+
+	case stateMagic2:
+		p.state = stateMagic1
+		if value == 0x33 {
+			p.state = stateCommand
+			p.sum = 0
+		}
+
+	case stateCommand:
+		p.command = value
+		p.sum += uint16(value)
+		p.state = stateCompression
+
+	case stateCompression:
+		p.compressed = value&0x01 == 0x01
+		p.sum += uint16(value)
+		p.state = stateLengthLo
+
+	case stateLengthLo:
+		p.length = uint16(value)
+		p.sum += uint16(value)
+		p.state = stateLengthHi
+
+	case stateLengthHi:
+		p.length |= uint16(value) << 8
+		p.sum += uint16(value)
+		p.data = p.data[:0]
+
+		p.state = stateData
+		if p.length == 0 {
+			p.state = stateChecksumLo
+		}
+
+	case stateData:
+		p.data = append(p.data, value)
+		p.sum += uint16(value)
+
+		if len(p.data) == int(p.length) {
+			p.state = stateChecksumLo
+		}
+
+	case stateChecksumLo:
+		p.checksum = uint16(value)
+		p.state = stateChecksumHi
+
+	case stateChecksumHi:
+		p.checksum |= uint16(value) << 8
+		p.state = stateAlive
+
+	case stateAlive:
+		p.state = stateStatus
+		return printerAlive
+
+	case stateStatus:
+		p.state = stateMagic1
+		p.execCommand()
+		return p.status
+	}
+
+	return 0x00
+}
+
+// Attach implements LinkCable.
+func (p *Printer) Attach(_ LinkPort) {
+	// NB: the printer never provides the clock so never needs to call into the port
+}
+
+// Close implements LinkCable.
+func (p *Printer) Close() error {
+	return nil
+}
+
+func (p *Printer) execCommand() {
+	if p.checksum != p.sum {
+		p.status |= StatusChecksumError
+		return
+	}
+
+	p.status &^= StatusChecksumError
+
+	switch p.command {
+	case CommandInit:
+		p.image = p.image[:0]
+		p.status = 0
+		p.printPolls = 0
+
+	case CommandData:
+		// NB: an empty data packet marks the end of the image
+		if len(p.data) == 0 {
+			p.status |= StatusImageFull
+			return
+		}
+
+		data := p.data
+		if p.compressed {
+			data = decompress(data)
+		}
+
+		if len(p.image)+len(data) > maxImageSize {
+			data = data[:maxImageSize-len(p.image)]
+		}
+
+		p.image = append(p.image, data...)
+		p.status |= StatusUnprocessed
+
+	case CommandPrint:
+		if len(p.data) < 4 {
+			return
+		}
+
+		p.print(p.data[2])
+
+	case CommandStatus:
+		if p.printPolls == 0 {
+			return
+		}
+
+		p.printPolls--
+		if p.printPolls == 0 {
+			p.status &^= StatusPrinting
+		}
+	}
+}
+
+func (p *Printer) print(palette uint8) {
+	p.status = p.status&^(StatusImageFull|StatusUnprocessed) | StatusPrinting
+	p.printPolls = printStatusPolls
+	p.printNumber++
+
+	image := append([]byte(nil), p.image...)
+	p.image = p.image[:0]
+
+	// NB: writing the png is slow enough to stall emulation so happens in the background
+	go func(number int) {
+		if err := render(p.outputDir, number, image, palette); err != nil {
+			log.Printf("failed to save print: %s", err)
+		}
+	}(p.printNumber)
+}
+
+// decompress expands run length encoded packet data
+//
+// a control byte with bit 7 set is followed by a single byte repeated (control & 0x7F) + 2 times
+// otherwise it is followed by control + 1 literal bytes
+func decompress(data []byte) []byte {
+	var out []byte
+
+	for i := 0; i < len(data); {
+		control := data[i]
+		i++
+
+		if control&0x80 == 0x80 {
+			if i >= len(data) {
+				break
+			}
+
+			for range int(control&0x7F) + 2 {
+				out = append(out, data[i])
+			}
+			i++
+			continue
+		}
+
+		end := min(len(data), i+int(control)+1)
+		out = append(out, data[i:end]...)
+		i = end
+	}
+
+	return out
+}
+
+var _ LinkCable = (*Printer)(nil)
@@ -0,0 +1,147 @@
+package printer
+
+import (
+	"bytes"
+	"testing"
+)
+
+// packet is a printer packet sent by the game, badChecksum corrupts the checksum sent with it
+type packet struct {
+	command     uint8
+	compressed  bool
+	data        []byte
+	badChecksum bool
+}
+
+// send feeds a packet to the printer a byte at a time and returns the alive and status replies
+func send(t *testing.T, p *Printer, pkt packet) (alive, status uint8) {
+	t.Helper()
+
+	var compression uint8
+	if pkt.compressed {
+		compression = 0x01
+	}
+
+	body := []byte{pkt.command, compression, uint8(len(pkt.data)), uint8(len(pkt.data) >> 8)}
+	body = append(body, pkt.data...)
+
+	var checksum uint16
+	for _, value := range body {
+		checksum += uint16(value)
+	}
+	if pkt.badChecksum {
+		checksum++
+	}
+
+	stream := append([]byte{0x88, 0x33}, body...)
+	stream = append(stream, uint8(checksum), uint8(checksum>>8))
+
+	for i, value := range stream {
+		if reply := p.Transfer(value); reply != 0x00 {
+			t.Fatalf("reply to byte %d = 0x%02X, want 0x00", i, reply)
+		}
+	}
+
+	return p.Transfer(0x00), p.Transfer(0x00)
+}
+
+func TestPrinter(t *testing.T) {
+	literal := []byte{0x01, 0x02, 0x03, 0x04}
+
+	cases := []struct {
+		name       string
+		packets    []packet
+		wantStatus uint8
+		wantImage  []byte
+	}{
+		{
+			name:    "init",
+			packets: []packet{{command: CommandInit}},
+		},
+		{
+			name:       "literal data",
+			packets:    []packet{{command: CommandInit}, {command: CommandData, data: literal}},
+			wantStatus: StatusUnprocessed,
+			wantImage:  literal,
+		},
+		{
+			name: "compressed data",
+			packets: []packet{
+				{command: CommandInit},
+				{command: CommandData, compressed: true, data: []byte{0x81, 0xAA, 0x02, 0x01, 0x02, 0x03, 0x80, 0xBB}},
+			},
+			wantStatus: StatusUnprocessed,
+			wantImage:  []byte{0xAA, 0xAA, 0xAA, 0x01, 0x02, 0x03, 0xBB, 0xBB},
+		},
+		{
+			name: "end of image",
+			packets: []packet{
+				{command: CommandInit},
+				{command: CommandData, data: literal},
+				{command: CommandData},
+			},
+			wantStatus: StatusUnprocessed | StatusImageFull,
+			wantImage:  literal,
+		},
+		{
+			name: "bad checksum",
+			packets: []packet{
+				{command: CommandInit},
+				{command: CommandData, data: literal, badChecksum: true},
+			},
+			wantStatus: StatusChecksumError,
+		},
+		{
+			name: "checksum error clears on the next good packet",
+			packets: []packet{
+				{command: CommandInit},
+				{command: CommandData, data: literal, badChecksum: true},
+				{command: CommandStatus},
+			},
+		},
+		{
+			name: "print",
+			packets: []packet{
+				{command: CommandInit},
+				{command: CommandData, data: literal},
+				{command: CommandPrint, data: []byte{0x01, 0x13, 0xE4, 0x40}},
+			},
+			wantStatus: StatusPrinting,
+		},
+		{
+			name: "printing clears after the status polls",
+			packets: []packet{
+				{command: CommandInit},
+				{command: CommandData, data: literal},
+				{command: CommandPrint, data: []byte{0x01, 0x13, 0xE4, 0x40}},
+				{command: CommandStatus},
+				{command: CommandStatus},
+				{command: CommandStatus},
+				{command: CommandStatus},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// NB: images smaller than a row of tiles are never written out
+			p := New(t.TempDir())
+
+			var alive, status uint8
+			for _, pkt := range tc.packets {
+				alive, status = send(t, p, pkt)
+
+				if alive != printerAlive {
+					t.Fatalf("alive reply = 0x%02X, want 0x%02X", alive, printerAlive)
+				}
+			}
+
+			if status != tc.wantStatus {
+				t.Fatalf("status = 0x%02X, want 0x%02X", status, tc.wantStatus)
+			}
+			if !bytes.Equal(p.image, tc.wantImage) {
+				t.Fatalf("image = % X, want % X", p.image, tc.wantImage)
+			}
+		})
+	}
+}
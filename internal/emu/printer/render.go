@@ -0,0 +1,70 @@
+package printer
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// the printer is always 20 tiles wide
+	printTilesWide = 20
+	printWidth     = printTilesWide * 8
+	tileSize       = 16
+)
+
+// grey levels for the 4 shades the print head can produce, lightest first
+var printShades = [4]uint8{0xFF, 0xAA, 0x55, 0x00}
+
+// render decodes the 2bpp tile data in the printer buffer and writes it to a png in dir
+//
+// palette maps colour indexes to shades in the same way as BGP, 0 is treated as the default 0xE4
+func render(dir string, number int, data []byte, palette uint8) error {
+	if palette == 0 {
+		palette = 0xE4
+	}
+
+	rows := len(data) / (printTilesWide * tileSize)
+	if rows == 0 {
+		return nil
+	}
+
+	img := image.NewGray(image.Rect(0, 0, printWidth, rows*8))
+
+	for tile := range rows * printTilesWide {
+		tileX := tile % printTilesWide * 8
+		tileY := tile / printTilesWide * 8
+		offset := tile * tileSize
+
+		for y := range 8 {
+			lo := data[offset+y*2]
+			hi := data[offset+y*2+1]
+
+			for x := range 8 {
+				bit := 7 - x
+				colorIdx := (hi>>bit&0x01)<<1 | lo>>bit&0x01
+				shade := palette >> (colorIdx * 2) & 0x03
+
+				img.SetGray(tileX+x, tileY+y, color.Gray{Y: printShades[shade]})
+			}
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("print-%s-%03d.png", time.Now().Format("20060102-150405"), number)
+
+	fh, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	return png.Encode(fh, img)
+}
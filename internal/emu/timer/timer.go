@@ -8,20 +8,55 @@ import (
 	. "github.com/indeedhat/gb-emulator/internal/emu/enum"
 )
 
+const (
+	TacEnable      = uint8(1) << 2
+	TacClockSelect = uint8(0x3)
+)
+
+// T-cycles between TIMA overflowing and it being reloaded from TMA
+const timaReloadDelay = 4
+
+// bit of the internal counter that drives TIMA for each TAC clock select value
+var tacCounterBits = [4]uint16{
+	1 << 9, // 4096 Hz
+	1 << 3, // 262144 Hz
+	1 << 5, // 65536 Hz
+	1 << 7, // 16384 Hz
+}
+
+// Timer is built around the 16 bit internal counter, DIV is its upper 8 bits
+//
+// TIMA is incremented on the falling edge of the counter bit selected by TAC and'ed with the
+// enable bit, so anything that changes either (writing DIV or TAC) can also increment TIMA.
+//
+// when TIMA overflows it reads 0 for one M-cycle before being reloaded from TMA and raising
+// the interrupt, writing TIMA in that window cancels the reload. for the M-cycle after the reload
+// TIMA follows TMA and writes to it are ignored
 type Timer struct {
 	div  uint16
 	tima uint8
 	tma  uint8
 	tac  uint8
 
+	// T-cycles left until a pending reload
+	reloadCountdown uint8
+	// T-cycles left in the window following a reload
+	reloadWindow uint8
+
 	ctx *context.Context
 }
 
 func New(ctx *context.Context) {
-	ctx.Timer = &Timer{
+	t := &Timer{
 		div: 0xABCC,
 		ctx: ctx,
 	}
+
+	if ctx.BootRom != nil {
+		t.div = 0
+	}
+
+	ctx.Timer = t
 }
 
 func (t *Timer) LoadState(data []byte) {
@@ -31,6 +66,8 @@ func (t *Timer) LoadState(data []byte) {
 	binary.Read(r, binary.BigEndian, &t.tima)
 	binary.Read(r, binary.BigEndian, &t.tma)
 	binary.Read(r, binary.BigEndian, &t.tac)
+	binary.Read(r, binary.BigEndian, &t.reloadCountdown)
+	binary.Read(r, binary.BigEndian, &t.reloadWindow)
 }
 
 func (t *Timer) SaveState() []byte {
@@ -40,54 +77,59 @@ func (t *Timer) SaveState() []byte {
 	binary.Write(&buf, binary.BigEndian, t.tima)
 	binary.Write(&buf, binary.BigEndian, t.tma)
 	binary.Write(&buf, binary.BigEndian, t.tac)
+	binary.Write(&buf, binary.BigEndian, t.reloadCountdown)
+	binary.Write(&buf, binary.BigEndian, t.reloadWindow)
 
 	return buf.Bytes()
 }
 
+// Tick is called once per T-cycle
 func (t *Timer) Tick() {
-	pdiv := t.div
-	t.div++
-
-	var (
-		clockDivider uint16
-		tacDivider   uint8 = 0x4
-		clockSelect        = t.tac & 0x3
-	)
-
-	switch clockSelect {
-	case 0x0:
-		clockDivider = 1 << 9
-	case 0x1:
-		clockDivider = 1 << 3
-	case 0x2:
-		clockDivider = 1 << 5
-	case 0x3:
-		clockDivider = 1 << 7
+	if t.reloadWindow > 0 {
+		t.reloadWindow--
 	}
 
-	if (pdiv&clockDivider) == clockDivider &&
-		(t.div&clockDivider) != clockDivider &&
-		(t.tac&tacDivider) == tacDivider {
+	if t.reloadCountdown > 0 {
+		t.reloadCountdown--
 
-		t.tima++
-		if t.tima == 0xFF {
+		if t.reloadCountdown == 0 {
 			t.tima = t.tma
-
+			t.reloadWindow = timaReloadDelay
 			t.ctx.Cpu.RequestInterrupt(InterruptTimer)
 		}
 	}
+
+	t.setCounter(t.div + 1)
 }
 
 func (t *Timer) Write(addr uint16, value uint8) {
 	switch addr {
 	case 0xFF04:
-		t.div = 0
+		t.setCounter(0)
+
 	case 0xFF05:
+		// NB: TMA is being copied into TIMA this cycle so the write is lost
+		if t.reloadWindow > 0 {
+			return
+		}
+
 		t.tima = value
+		t.reloadCountdown = 0
+
 	case 0xFF06:
 		t.tma = value
+
+		if t.reloadWindow > 0 {
+			t.tima = value
+		}
+
 	case 0xFF07:
-		t.tac = value
+		before := t.timaSignal()
+		t.tac = value & (TacEnable | TacClockSelect)
+
+		if before && !t.timaSignal() {
+			t.incrementTima()
+		}
 	}
 }
 
@@ -100,8 +142,31 @@ func (t *Timer) Read(addr uint16) uint8 {
 	case 0xFF06:
 		return t.tma
 	case 0xFF07:
-		return t.tac
+		return t.tac | 0xF8
 	default:
 		panic("bad timer address")
 	}
 }
+
+// setCounter updates the internal counter and increments TIMA on a falling edge
+func (t *Timer) setCounter(value uint16) {
+	before := t.timaSignal()
+	t.div = value
+
+	if before && !t.timaSignal() {
+		t.incrementTima()
+	}
+}
+
+// timaSignal is the output of the multiplexer that feeds the TIMA falling edge detector
+func (t *Timer) timaSignal() bool {
+	return t.tac&TacEnable != 0 && t.div&tacCounterBits[t.tac&TacClockSelect] != 0
+}
+
+func (t *Timer) incrementTima() {
+	t.tima++
+
+	if t.tima == 0 {
+		t.reloadCountdown = timaReloadDelay
+	}
+}
@@ -0,0 +1,213 @@
+package timer
+
+import (
+	"testing"
+
+	"github.com/indeedhat/gb-emulator/internal/emu/context"
+	. "github.com/indeedhat/gb-emulator/internal/emu/enum"
+)
+
+// stubCpu only records the interrupts requested by the timer
+type stubCpu struct {
+	interruptFlags uint8
+}
+
+func (c *stubCpu) RequestInterrupt(itype uint8)     { c.interruptFlags |= itype }
+func (c *stubCpu) Step() error                      { return nil }
+func (c *stubCpu) InterruptFlags() uint8            { return c.interruptFlags }
+func (c *stubCpu) SetInterruptFlags(value uint8)    { c.interruptFlags = value }
+func (c *stubCpu) InterruptRegister() uint8         { return 0 }
+func (c *stubCpu) SetInterruptRegister(value uint8) {}
+func (c *stubCpu) DoubleSpeed() bool                { return false }
+func (c *stubCpu) Key1() uint8                      { return 0xFF }
+func (c *stubCpu) SetKey1(value uint8)              {}
+
+func newTestTimer() (*Timer, *stubCpu) {
+	cpu := &stubCpu{}
+	ctx := &context.Context{Cpu: cpu}
+	New(ctx)
+
+	t := ctx.Timer.(*Timer)
+	// NB: start every test from a known counter value rather than the post boot one
+	t.Write(0xFF04, 0)
+
+	return t, cpu
+}
+
+func tick(t *Timer, n int) {
+	for range n {
+		t.Tick()
+	}
+}
+
+// overflow sets the timer up to overflow TIMA on the next tick using the 262144 Hz clock
+func overflow(t *Timer, tma uint8) {
+	t.Write(0xFF06, tma)
+	t.Write(0xFF05, 0xFF)
+	t.Write(0xFF07, TacEnable|0x01)
+	tick(t, 16)
+}
+
+func TestTimerFallingEdge(t *testing.T) {
+	cases := []struct {
+		name     string
+		run      func(tm *Timer)
+		wantTima uint8
+	}{
+		{
+			name: "div write with selected bit set",
+			run: func(tm *Timer) {
+				tm.Write(0xFF07, TacEnable|0x01)
+				tick(tm, 8)
+				tm.Write(0xFF04, 0)
+			},
+			wantTima: 1,
+		},
+		{
+			name: "div write with selected bit clear",
+			run: func(tm *Timer) {
+				tm.Write(0xFF07, TacEnable|0x01)
+				tick(tm, 4)
+				tm.Write(0xFF04, 0)
+			},
+			wantTima: 0,
+		},
+		{
+			name: "div write while disabled",
+			run: func(tm *Timer) {
+				tm.Write(0xFF07, 0x01)
+				tick(tm, 8)
+				tm.Write(0xFF04, 0)
+			},
+			wantTima: 0,
+		},
+		{
+			name: "tac disable with selected bit set",
+			run: func(tm *Timer) {
+				tm.Write(0xFF07, TacEnable|0x01)
+				tick(tm, 8)
+				tm.Write(0xFF07, 0x01)
+			},
+			wantTima: 1,
+		},
+		{
+			name: "tac clock select to a clear bit",
+			run: func(tm *Timer) {
+				tm.Write(0xFF07, TacEnable|0x01)
+				tick(tm, 8)
+				tm.Write(0xFF07, TacEnable|0x00)
+			},
+			wantTima: 1,
+		},
+		{
+			name: "tac rewrite with the same value",
+			run: func(tm *Timer) {
+				tm.Write(0xFF07, TacEnable|0x01)
+				tick(tm, 8)
+				tm.Write(0xFF07, TacEnable|0x01)
+			},
+			wantTima: 0,
+		},
+		{
+			name: "counter falling edge",
+			run: func(tm *Timer) {
+				tm.Write(0xFF07, TacEnable|0x01)
+				tick(tm, 16)
+			},
+			wantTima: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tm, _ := newTestTimer()
+			tc.run(tm)
+
+			if tima := tm.Read(0xFF05); tima != tc.wantTima {
+				t.Fatalf("TIMA = %d, want %d", tima, tc.wantTima)
+			}
+		})
+	}
+}
+
+func TestTimerReload(t *testing.T) {
+	cases := []struct {
+		name          string
+		run           func(tm *Timer)
+		wantTima      uint8
+		wantInterrupt bool
+	}{
+		{
+			name:     "reads zero after overflow",
+			run:      func(tm *Timer) {},
+			wantTima: 0x00,
+		},
+		{
+			name: "still zero one T-cycle before the reload",
+			run: func(tm *Timer) {
+				tick(tm, timaReloadDelay-1)
+			},
+			wantTima: 0x00,
+		},
+		{
+			name: "reloads and requests the interrupt after one M-cycle",
+			run: func(tm *Timer) {
+				tick(tm, timaReloadDelay)
+			},
+			wantTima:      0x42,
+			wantInterrupt: true,
+		},
+		{
+			name: "tima write cancels the reload",
+			run: func(tm *Timer) {
+				tick(tm, 2)
+				tm.Write(0xFF05, 0x10)
+				tick(tm, 8)
+			},
+			wantTima: 0x10,
+		},
+		{
+			name: "tima write during the reload window is ignored",
+			run: func(tm *Timer) {
+				tick(tm, timaReloadDelay)
+				tm.Write(0xFF05, 0x10)
+			},
+			wantTima:      0x42,
+			wantInterrupt: true,
+		},
+		{
+			name: "tma write during the reload window propagates",
+			run: func(tm *Timer) {
+				tick(tm, timaReloadDelay)
+				tm.Write(0xFF06, 0x99)
+			},
+			wantTima:      0x99,
+			wantInterrupt: true,
+		},
+		{
+			name: "tma write after the reload window does not propagate",
+			run: func(tm *Timer) {
+				tick(tm, timaReloadDelay*2)
+				tm.Write(0xFF06, 0x99)
+			},
+			wantTima:      0x42,
+			wantInterrupt: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tm, cpu := newTestTimer()
+			overflow(tm, 0x42)
+			tc.run(tm)
+
+			if tima := tm.Read(0xFF05); tima != tc.wantTima {
+				t.Fatalf("TIMA = 0x%02X, want 0x%02X", tima, tc.wantTima)
+			}
+
+			if got := cpu.interruptFlags&InterruptTimer != 0; got != tc.wantInterrupt {
+				t.Fatalf("timer interrupt requested = %t, want %t", got, tc.wantInterrupt)
+			}
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package types
 
+import "image"
+
 type ReadWriter interface {
 	Read(address uint16) uint8
 	Write(address uint16, value uint8)
@@ -38,3 +40,56 @@ type MBC interface {
 	SaveLoader
 	Stator
 }
+
+// Rumbler is implemented by carts with a rumble motor
+type Rumbler interface {
+	// RumbleCh emits the new motor state each time the cart turns it on or off
+	// events are dropped rather than stalling the emulator if nothing is listening
+	RumbleCh() <-chan bool
+}
+
+// InfraredEmitter is implemented by carts with an infrared led and sensor
+//
+// there is no ir peer so the sensor never sees any light, the HuC carts read this as 0xC0
+type InfraredEmitter interface {
+	// IrLedCh emits the new led state each time the cart turns it on or off
+	// events are dropped in the same way as RumbleCh
+	IrLedCh() <-chan bool
+}
+
+type Accelerometer interface {
+	// SetTilt is called from the input goroutine rather than the emulation thread
+	SetTilt(event TiltEvent)
+}
+
+// CameraSource provides the image seen by the pocket camera sensor
+type CameraSource interface {
+	Frame() image.Image
+}
+
+type CameraSensor interface {
+	SetCameraSource(source CameraSource)
+}
+
+// LinkCable connects the serial port to a peer
+type LinkCable interface {
+	// Transfer sends the byte shifted out by the side providing the clock and returns the byte
+	// shifted back in from the peer, 0xFF is returned when nothing is connected
+	// it may block waiting for the peer so is never called on the emulation thread
+	Transfer(value uint8) uint8
+	// Attach registers the local port that answers transfers clocked by the peer
+	Attach(port LinkPort)
+	Close() error
+}
+
+// LinkPort is the local end of a link cable
+type LinkPort interface {
+	// ExternalTransfer is called when the peer clocks a byte into this port
+	// it returns the byte shifted out in exchange
+	ExternalTransfer(value uint8) uint8
+}
+
+type AudioSink interface {
+	Write(samples []Sample) error
+	Close() error
+}
@@ -0,0 +1,6 @@
+package types
+
+type Sample struct {
+	Left  int16
+	Right int16
+}
@@ -0,0 +1,10 @@
+package types
+
+// TiltEvent describes the current tilt of the console in g
+//
+// X is positive when tilted to the right and Y is positive when tilted towards the player,
+// both are clamped to the range -1 to 1
+type TiltEvent struct {
+	X float32
+	Y float32
+}
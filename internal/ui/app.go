@@ -12,8 +12,12 @@ import (
 	"github.com/sqweek/dialog"
 
 	"github.com/indeedhat/gb-emulator/internal/emu"
+	"github.com/indeedhat/gb-emulator/internal/emu/audio"
+	"github.com/indeedhat/gb-emulator/internal/emu/camera"
 	"github.com/indeedhat/gb-emulator/internal/emu/context"
 	"github.com/indeedhat/gb-emulator/internal/emu/enum"
+	"github.com/indeedhat/gb-emulator/internal/emu/link"
+	"github.com/indeedhat/gb-emulator/internal/emu/printer"
 	"github.com/indeedhat/gb-emulator/internal/emu/types"
 )
 
@@ -31,6 +35,10 @@ type App struct {
 	stateSlot       int
 	stateSlotRotate bool
 	stateAutoSave   bool
+
+	tilt types.TiltEvent
+
+	opts emu.Options
 }
 
 func (a *App) renderLoop() {
@@ -61,6 +69,23 @@ func (a *App) autosaveLoop() {
 	}
 }
 
+// rumbleLoop shows the state of the cart's rumble motor in the window title
+func (a *App) rumbleLoop(rumbler types.Rumbler, done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			a.window.SetTitle("Emulator")
+			return
+		case on := <-rumbler.RumbleCh():
+			if on {
+				a.window.SetTitle("Emulator (rumble)")
+			} else {
+				a.window.SetTitle("Emulator")
+			}
+		}
+	}
+}
+
 func (a *App) handleLoadRom(filename string) func() {
 	return func() {
 		var err error
@@ -75,32 +100,110 @@ func (a *App) handleLoadRom(filename string) func() {
 				return
 			}
 
-			filename, err = dialog.File().SetStartDir(dir).Filter(".gb files", "gb").Load()
+			filename, err = dialog.File().SetStartDir(dir).Filter("Game Boy roms", "gb", "gbc").Load()
 			if err != nil {
 				fynedialog.ShowError(err, a.window)
 				return
 			}
 		}
 
-		a.emu, a.ctx, err = emu.NewEmulator(filename, false)
+		a.emu, a.ctx, err = emu.NewEmulator(filename, a.resolveBootRoms(), false)
 		if err != nil {
 			fynedialog.ShowError(err, a.window)
 			a.handleStopEmulation()
 			return
 		}
 
+		if path := a.resolveCameraSource(); path != "" {
+			source, err := camera.Open(path)
+			if err != nil {
+				fynedialog.ShowError(err, a.window)
+			} else {
+				a.emu.AttachCameraSource(source)
+			}
+		}
+
+		if a.opts.AudioWav != "" {
+			sink, err := audio.RecordWav(a.opts.AudioWav, uint32(a.opts.AudioRate))
+			if err != nil {
+				fynedialog.ShowError(err, a.window)
+			} else {
+				a.emu.AttachAudioSink(sink)
+			}
+		}
+
 		a.done = make(chan struct{})
 
+		if a.opts.LinkListen != "" || a.opts.LinkDial != "" {
+			go a.connectLinkCable(a.emu)
+		} else if dir := a.resolvePrinterDir(); dir != "" {
+			a.emu.AttachLinkCable(printer.New(dir))
+		}
+
 		go a.emu.Run()
 		go a.renderLoop()
 		go a.autosaveLoop()
 
+		if rumbler, ok := a.ctx.Cart.Mbc().(types.Rumbler); ok {
+			go a.rumbleLoop(rumbler, a.done)
+		}
+
 		a.menu.TriggerEmuRunnung()
 		a.menu.TriggerRecentReload(filename)
 		a.menu.TriggerStateReload()
 	}
 }
 
+// connectLinkCable connects to the peer emulator, when listening this blocks until the peer connects
+func (a *App) connectLinkCable(e *emu.Emulator) {
+	var (
+		cable *link.NetCable
+		err   error
+	)
+
+	if a.opts.LinkListen != "" {
+		cable, err = link.Listen(a.opts.LinkListen)
+	} else {
+		cable, err = link.Dial(a.opts.LinkDial)
+	}
+
+	if err != nil {
+		fynedialog.ShowError(err, a.window)
+		return
+	}
+
+	e.AttachLinkCable(cable)
+}
+
+func (a *App) resolvePrinterDir() string {
+	if a.opts.PrinterDir != "" {
+		return a.opts.PrinterDir
+	}
+
+	return a.runner.Preferences().String(PrefPrinterDir)
+}
+
+func (a *App) resolveCameraSource() string {
+	if a.opts.CameraSource != "" {
+		return a.opts.CameraSource
+	}
+
+	return a.runner.Preferences().String(PrefCameraSource)
+}
+
+func (a *App) resolveBootRoms() emu.BootRoms {
+	bootRoms := a.opts.BootRoms
+
+	if bootRoms.Dmg == "" {
+		bootRoms.Dmg = a.runner.Preferences().String(PrefBootRomDmg)
+	}
+	if bootRoms.Cgb == "" {
+		bootRoms.Cgb = a.runner.Preferences().String(PrefBootRomCgb)
+	}
+
+	return bootRoms
+}
+
 func (a *App) handleAutosaveToggle() bool {
 	current := a.runner.Preferences().Bool(PrefAutoSaveState)
 	a.runner.Preferences().SetBool(PrefAutoSaveState, !current)
@@ -158,6 +261,10 @@ func (a *App) handleKeyUp(e *fyne.KeyEvent) {
 		return
 	}
 
+	if a.handleTilt(e, false) {
+		return
+	}
+
 	code := mapKeyCode(a.runner.Preferences(), e)
 	if code == enum.KeyUnknown {
 		return
@@ -174,6 +281,10 @@ func (a *App) handleKeyDown(e *fyne.KeyEvent) {
 		return
 	}
 
+	if a.handleTilt(e, true) {
+		return
+	}
+
 	code := mapKeyCode(a.runner.Preferences(), e)
 	if code == enum.KeyUnknown {
 		return
@@ -184,3 +295,32 @@ func (a *App) handleKeyDown(e *fyne.KeyEvent) {
 		Down: true,
 	}
 }
+
+// handleTilt updates the tilt of the console from the keyboard
+// it reports if the key was a tilt key
+func (a *App) handleTilt(e *fyne.KeyEvent, down bool) bool {
+	x, y, ok := mapTiltKey(a.runner.Preferences(), e)
+	if !ok {
+		return false
+	}
+
+	if x != 0 {
+		if down {
+			a.tilt.X = x
+		} else if a.tilt.X == x {
+			a.tilt.X = 0
+		}
+	}
+
+	if y != 0 {
+		if down {
+			a.tilt.Y = y
+		} else if a.tilt.Y == y {
+			a.tilt.Y = 0
+		}
+	}
+
+	a.ctx.TiltCh <- a.tilt
+
+	return true
+}
@@ -22,6 +22,13 @@ const (
 	PrefRecentRomsCount         = "recent-roms.count"
 	PrefRecentRomsCountFallback = 5
 
+	PrefCameraSource = "camera.source"
+
+	PrefPrinterDir = "printer.dir"
+
+	PrefBootRomDmg = "boot-rom.dmg"
+	PrefBootRomCgb = "boot-rom.cgb"
+
 	PrefControlsA              = "controls.a"
 	PrefControlsAFallback      = "X"
 	PrefControlsB              = "controls.b"
@@ -38,6 +45,15 @@ const (
 	PrefControlsStartFallback  = "Return"
 	PrefControlsSelect         = "controls.select"
 	PrefControlsSelectFallback = "Space"
+
+	PrefControlsTiltUp            = "controls.tilt-up"
+	PrefControlsTiltUpFallback    = "I"
+	PrefControlsTiltDown          = "controls.tilt-down"
+	PrefControlsTiltDownFallback  = "K"
+	PrefControlsTiltLeft          = "controls.tilt-left"
+	PrefControlsTiltLeftFallback  = "J"
+	PrefControlsTiltRight         = "controls.tilt-right"
+	PrefControlsTiltRightFallback = "L"
 )
 
 type Preferences struct {
@@ -57,6 +73,9 @@ func NewPreferencesWindow(runner fyne.App) *Preferences {
 		p.initAutosaveSection(),
 		p.initRecentSection(),
 		p.initControlsSection(),
+		p.initBootRomSection(),
+		p.initCameraSection(),
+		p.initPrinterSection(),
 	))
 
 	return p
@@ -127,6 +146,81 @@ func (p *Preferences) initRecentSection() *fyne.Container {
 	)
 }
 
+func (p *Preferences) initBootRomSection() *fyne.Container {
+	title := widget.NewLabel("Boot ROM")
+	title.TextStyle.Bold = true
+	title.TextStyle.Underline = true
+
+	dmgLabel := widget.NewLabel("DMG (leave empty to skip the boot sequence)")
+	dmg := widget.NewEntry()
+	dmg.SetText(p.runner.Preferences().String(PrefBootRomDmg))
+	dmg.OnChanged = func(s string) {
+		p.runner.Preferences().SetString(PrefBootRomDmg, s)
+	}
+
+	cgbLabel := widget.NewLabel("CGB (leave empty to skip the boot sequence)")
+	cgb := widget.NewEntry()
+	cgb.SetText(p.runner.Preferences().String(PrefBootRomCgb))
+	cgb.OnChanged = func(s string) {
+		p.runner.Preferences().SetString(PrefBootRomCgb, s)
+	}
+
+	spacer := canvas.NewLine(color.White)
+
+	return container.NewVBox(
+		title,
+		dmgLabel,
+		dmg,
+		cgbLabel,
+		cgb,
+		spacer,
+	)
+}
+
+func (p *Preferences) initCameraSection() *fyne.Container {
+	title := widget.NewLabel("Pocket Camera")
+	title.TextStyle.Bold = true
+	title.TextStyle.Underline = true
+
+	label := widget.NewLabel("Image or directory of png frames")
+	source := widget.NewEntry()
+	source.SetText(p.runner.Preferences().String(PrefCameraSource))
+	source.OnChanged = func(s string) {
+		p.runner.Preferences().SetString(PrefCameraSource, s)
+	}
+
+	spacer := canvas.NewLine(color.White)
+
+	return container.NewVBox(
+		title,
+		label,
+		source,
+		spacer,
+	)
+}
+
+func (p *Preferences) initPrinterSection() *fyne.Container {
+	title := widget.NewLabel("Game Boy Printer")
+	title.TextStyle.Bold = true
+	title.TextStyle.Underline = true
+
+	label := widget.NewLabel("Directory to save prints to, leave empty to disconnect the printer")
+	dir := widget.NewEntry()
+	dir.SetText(p.runner.Preferences().String(PrefPrinterDir))
+	dir.OnChanged = func(s string) {
+		p.runner.Preferences().SetString(PrefPrinterDir, s)
+	}
+
+	spacer := canvas.NewLine(color.White)
+
+	return container.NewVBox(
+		title,
+		label,
+		dir,
+		spacer,
+	)
+}
+
 func (p *Preferences) initControlsSection() *fyne.Container {
 	title := widget.NewLabel("Controls")
 	title.TextStyle.Bold = true
@@ -140,6 +234,10 @@ func (p *Preferences) initControlsSection() *fyne.Container {
 	rightLabel, rightButton := p.initControlButton("Right Button", PrefControlsRight, PrefControlsRightFallback)
 	startLabel, startButton := p.initControlButton("Start Button", PrefControlsStart, PrefControlsStartFallback)
 	selectLabel, selectButton := p.initControlButton("Select Button", PrefControlsSelect, PrefControlsSelectFallback)
+	tiltUpLabel, tiltUpButton := p.initControlButton("Tilt Up", PrefControlsTiltUp, PrefControlsTiltUpFallback)
+	tiltDownLabel, tiltDownButton := p.initControlButton("Tilt Down", PrefControlsTiltDown, PrefControlsTiltDownFallback)
+	tiltLeftLabel, tiltLeftButton := p.initControlButton("Tilt Left", PrefControlsTiltLeft, PrefControlsTiltLeftFallback)
+	tiltRightLabel, tiltRightButton := p.initControlButton("Tilt Right", PrefControlsTiltRight, PrefControlsTiltRightFallback)
 
 	spacer := canvas.NewLine(color.White)
 
@@ -161,6 +259,14 @@ func (p *Preferences) initControlsSection() *fyne.Container {
 		startButton,
 		selectLabel,
 		selectButton,
+		tiltUpLabel,
+		tiltUpButton,
+		tiltDownLabel,
+		tiltDownButton,
+		tiltLeftLabel,
+		tiltLeftButton,
+		tiltRightLabel,
+		tiltRightButton,
 		spacer,
 	)
 }
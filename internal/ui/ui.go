@@ -8,12 +8,13 @@ import (
 	fynecanvas "fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/driver/desktop"
 
+	"github.com/indeedhat/gb-emulator/internal/emu"
 	"github.com/indeedhat/gb-emulator/internal/emu/config"
 	"github.com/indeedhat/gb-emulator/internal/emu/enum"
 	"github.com/indeedhat/gb-emulator/internal/emu/types"
 )
 
-func NewFyneRenderer() (fyne.App, fyne.Window) {
+func NewFyneRenderer(opts emu.Options) (fyne.App, fyne.Window) {
 	runner := fyneapp.NewWithID("dev.indeedhat.gb-emu")
 
 	win := runner.NewWindow("Emulator")
@@ -28,6 +29,8 @@ func NewFyneRenderer() (fyne.App, fyne.Window) {
 		window: win,
 		runner: runner,
 		frame:  im,
+
+		opts: opts,
 	}
 	app.menu = NewMenu(runner, app)
 
@@ -75,3 +78,19 @@ func mapKeyCode(p fyne.Preferences, e *fyne.KeyEvent) enum.KeyCode {
 
 	return enum.KeyUnknown
 }
+
+// mapTiltKey returns the direction the console is tilted in while the given key is held
+func mapTiltKey(p fyne.Preferences, e *fyne.KeyEvent) (x, y float32, ok bool) {
+	switch e.Name {
+	case fyne.KeyName(p.StringWithFallback(PrefControlsTiltUp, PrefControlsTiltUpFallback)):
+		return 0, -1, true
+	case fyne.KeyName(p.StringWithFallback(PrefControlsTiltDown, PrefControlsTiltDownFallback)):
+		return 0, 1, true
+	case fyne.KeyName(p.StringWithFallback(PrefControlsTiltLeft, PrefControlsTiltLeftFallback)):
+		return -1, 0, true
+	case fyne.KeyName(p.StringWithFallback(PrefControlsTiltRight, PrefControlsTiltRightFallback)):
+		return 1, 0, true
+	}
+
+	return 0, 0, false
+}